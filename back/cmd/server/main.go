@@ -3,14 +3,20 @@ package main
 import (
 	"culture-festival-backend/config"
 	"culture-festival-backend/internal/api"
+	"culture-festival-backend/internal/domain"
 	"culture-festival-backend/internal/repo"
 	"culture-festival-backend/internal/storage"
 	"culture-festival-backend/internal/ws"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 同じクライアントIPから同じ作品へのいいねを1回と数える期間
+const likeDedupTTL = 24 * time.Hour
+
 func main() {
 	// 設定を読み込み
 	cfg := config.Load()
@@ -22,36 +28,93 @@ func main() {
 	}
 
 	// Redis接続
-	_, err = repo.NewRedisClient(cfg.RedisURL)
+	redisClient, err := repo.NewRedisClient(cfg.RedisURL)
 	if err != nil {
 		log.Fatal("Failed to connect to Redis:", err)
 	}
 
 	// 画像プロセッサー
-	imageProc := storage.NewImageProcessor(cfg.AssetDir)
-
-	// WebSocketハブ
-	hub := ws.NewHub()
-	go hub.Run()
+	imageProc := storage.NewImageProcessor(cfg.AssetDir, storage.UploadLimits{
+		MaxBytes:     cfg.UploadMaxBytes,
+		MaxPixels:    cfg.UploadMaxPixels,
+		AllowedMimes: cfg.UploadAllowedMimes,
+	})
 
 	// リポジトリを作成
 	artworkRepo := repo.NewArtworkRepository(db.DB)
 	assetRepo := repo.NewAssetRepository(db.DB)
 	sceneRepo := repo.NewSceneRepository(db.DB)
 	entityRepo := repo.NewSceneEntityRepository(db.DB)
+	apiKeyRepo := repo.NewAPIKeyRepository(db.DB)
+	displayRepo := repo.NewDisplayNodeRepository(db.DB)
+	stateRepo := repo.NewEntityStateRepository(redisClient)
+	revisionRepo := repo.NewSceneRevisionRepository(redisClient)
+	clockRepo := repo.NewSceneClockRepository(redisClient)
+	kindRepo := repo.NewAnimationKindRepository(db.DB)
+	assigner := repo.NewAnimationAssigner(db.DB, kindRepo)
+	fightRepo := repo.NewFightRepository(db.DB)
+	snapshotRepo := repo.NewSceneSnapshotRepository(db.DB)
+	capacityRepo := repo.NewCapacityRepository(db.DB)
+	moderationRepo := repo.NewModerationRepository(db.DB)
+	takedownRepo := repo.NewTakedownRepository(db.DB)
+	auditRepo := repo.NewAuditRepository(db.DB)
+	rateLimiter := repo.NewRateLimiter(redisClient)
+
+	// 配信バックエンド（redisにすると複数インスタンスで同じルームを共有できる）
+	var broadcaster ws.Broadcaster
+	if cfg.BroadcastBackend == "redis" {
+		broadcaster = repo.NewRoomPubSub(redisClient)
+		log.Println("Using Redis pub/sub broadcast backend")
+	}
+
+	// WebSocketハブ
+	hub := ws.NewHub(displayRepo, entityRepo, stateRepo, revisionRepo, clockRepo, fightRepo, capacityRepo, broadcaster)
+	go hub.Run()
 
 	// ハンドラーを作成
-	artworkHandler := api.NewArtworkHandler(artworkRepo, assetRepo, sceneRepo, entityRepo, imageProc, hub)
-	sceneHandler := api.NewSceneHandler(sceneRepo, entityRepo, hub)
+	artworkHandler := api.NewArtworkHandler(artworkRepo, assetRepo, sceneRepo, entityRepo, imageProc, assigner, moderationRepo, takedownRepo, hub)
+	sceneHandler := api.NewSceneHandler(sceneRepo, artworkRepo, entityRepo, stateRepo, kindRepo, assigner, snapshotRepo, capacityRepo, hub)
+	apiKeyHandler := api.NewAPIKeyHandler(apiKeyRepo, hub)
+	displayHandler := api.NewDisplayHandler(displayRepo, sceneRepo, hub)
+	fightHandler := api.NewFightHandler(fightRepo)
+	snapshotHandler := api.NewSnapshotHandler(sceneRepo, entityRepo, stateRepo, snapshotRepo, hub)
+	animationKindHandler := api.NewAnimationKindHandler(kindRepo)
+	auditHandler := api.NewAuditHandler(auditRepo)
+
+	// 認証ミドルウェア
+	auth := api.NewAuthMiddleware(apiKeyRepo)
+	audit := api.NewAuditMiddleware(auditRepo)
+	go audit.RunRetention(time.Duration(cfg.AuditRetentionDays) * 24 * time.Hour)
+	rateLimit := api.NewRateLimitMiddleware(rateLimiter)
+	uploadRateIP := repo.RateLimit{PerMinute: cfg.UploadRatePerMinuteIP, Burst: cfg.UploadBurstIP}
+	uploadRateKey := repo.RateLimit{PerMinute: cfg.UploadRatePerMinuteKey, Burst: cfg.UploadBurstKey}
+	likeRateIP := repo.RateLimit{PerMinute: cfg.LikeRatePerMinuteIP, Burst: cfg.LikeBurstIP}
 
 	// Ginルーターを設定
 	r := gin.Default()
 
+	// レート制限のクライアントIPを偽装されないよう、X-Forwarded-Forは信用するプロキシからのものだけ使う
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	// 信用しないピアからX-Forwarded-Forが来たら一度だけ警告する
+	// プロキシを挟んでいるのに設定し忘れると、全員がプロキシのIPとして同じ上限を共有してしまう
+	var untrustedProxyOnce sync.Once
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Forwarded-For") != "" && c.ClientIP() == c.RemoteIP() {
+			untrustedProxyOnce.Do(func() {
+				log.Printf("Ignoring X-Forwarded-For from untrusted peer %s; add it to TRUSTED_PROXIES if it is your proxy", c.RemoteIP())
+			})
+		}
+		c.Next()
+	})
+
 	// CORS設定
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		c.Header("Access-Control-Expose-Headers", "Retry-After")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -61,6 +124,9 @@ func main() {
 		c.Next()
 	})
 
+	// 状態を変更するリクエストを監査ログに記録
+	r.Use(audit.Record())
+
 	// 静的ファイル配信
 	r.Static("/assets", cfg.AssetDir)
 
@@ -70,31 +136,104 @@ func main() {
 		// アートワーク関連
 		artworks := apiGroup.Group("/artworks")
 		{
-			artworks.POST("", artworkHandler.Upload)
+			artworks.POST("", auth.RequireRoles(domain.RoleUpload, domain.RoleOps), rateLimit.Limit("upload", uploadRateIP, uploadRateKey), artworkHandler.Upload)
 			artworks.GET("", artworkHandler.GetAll)
 			artworks.GET("/:id", artworkHandler.GetByID)
-			artworks.DELETE("/:id", artworkHandler.Delete)
+			artworks.DELETE("/:id", auth.RequireRoles(domain.RoleOps), artworkHandler.Delete)
+			artworks.POST("/:id/takedown", auth.RequireRoles(domain.RoleOps), artworkHandler.Takedown)
+			artworks.POST("/:id/reinstate", auth.RequireRoles(domain.RoleOps), artworkHandler.Reinstate)
+			artworks.GET("/:id/takedowns", auth.RequireRoles(domain.RoleOps), artworkHandler.GetTakedownHistory)
+		}
+
+		// 取り下げ中のアートワーク
+		apiGroup.GET("/takedowns", auth.RequireRoles(domain.RoleOps), artworkHandler.ListTakedowns)
+
+		// 審査（承認制のシーン）
+		moderation := apiGroup.Group("/moderation", auth.RequireRoles(domain.RoleOps))
+		{
+			moderation.GET("/pending", artworkHandler.ListPending)
+			moderation.POST("/approve", artworkHandler.Approve)
+			moderation.POST("/reject", artworkHandler.Reject)
 		}
 
 		// シーン関連
 		scenes := apiGroup.Group("/scenes")
 		{
-			scenes.POST("", sceneHandler.CreateScene)
 			scenes.GET("", sceneHandler.GetScenes)
 			scenes.GET("/:id", sceneHandler.GetSceneByID)
-			scenes.POST("/:id/entities", sceneHandler.AddEntity)
-			scenes.PUT("/:id/entities/:entity_id", sceneHandler.UpdateEntity)
-			scenes.DELETE("/:id/entities/:entity_id", sceneHandler.DeleteEntity)
-			scenes.POST("/:id/reset", sceneHandler.ResetScene)
+			// display-appは20体到達時の自動リセットでエンティティを削除する
+			scenes.DELETE("/:id/entities/:entity_id", auth.RequireRoles(domain.RoleOps, domain.RoleDisplay), sceneHandler.DeleteEntity)
+
+			opsScenes := scenes.Group("", auth.RequireRoles(domain.RoleOps))
+			opsScenes.POST("", sceneHandler.CreateScene)
+			opsScenes.PUT("/:id", sceneHandler.UpdateScene)
+			opsScenes.DELETE("/:id", sceneHandler.DeleteScene)
+			opsScenes.POST("/:id/clone", sceneHandler.CloneScene)
+			opsScenes.POST("/:id/entities", sceneHandler.AddEntity)
+			opsScenes.PUT("/:id/entities/:entity_id", sceneHandler.UpdateEntity)
+			opsScenes.POST("/:id/reset", sceneHandler.ResetScene)
+			opsScenes.PUT("/:id/animation-policy", sceneHandler.UpdateAnimationPolicy)
+			opsScenes.PUT("/:id/capacity", sceneHandler.UpdateCapacity)
+			opsScenes.GET("/:id/queue", sceneHandler.GetQueue)
+			opsScenes.GET("/:id/state", sceneHandler.GetSceneState)
+			opsScenes.GET("/:id/snapshots", snapshotHandler.List)
+			opsScenes.POST("/:id/snapshots", snapshotHandler.Create)
+			opsScenes.POST("/:id/snapshots/:snapshot_id/restore", snapshotHandler.Restore)
+			opsScenes.DELETE("/:id/snapshots/:snapshot_id", snapshotHandler.Delete)
+			opsScenes.GET("/:id/trash", snapshotHandler.ListTrash)
+			opsScenes.POST("/:id/trash/:snapshot_id/restore", snapshotHandler.UndoReset)
+		}
+
+		// シーン指定のないアップロードの振り分け先
+		uploadScenes := apiGroup.Group("/upload-scenes", auth.RequireRoles(domain.RoleOps))
+		{
+			uploadScenes.GET("", sceneHandler.GetUploadScenes)
+			uploadScenes.PUT("", sceneHandler.SetUploadScenes)
+		}
+
+		// アニメーション種類レジストリ
+		animationKinds := apiGroup.Group("/animation-kinds")
+		{
+			animationKinds.GET("", animationKindHandler.List)
+			animationKinds.POST("", auth.RequireRoles(domain.RoleOps), animationKindHandler.Create)
+			animationKinds.PUT("/:name", auth.RequireRoles(domain.RoleOps), animationKindHandler.Update)
 		}
+
+		// spin_fight
+		apiGroup.GET("/fights/leaderboard", fightHandler.Leaderboard)
+
+		// ディスプレイノード関連
+		displays := apiGroup.Group("/displays", auth.RequireRoles(domain.RoleOps))
+		{
+			displays.POST("", displayHandler.Create)
+			displays.GET("", displayHandler.List)
+			displays.GET("/:id", displayHandler.GetByID)
+			displays.PUT("/:id", displayHandler.Update)
+			displays.DELETE("/:id", displayHandler.Delete)
+			displays.POST("/:id/push", displayHandler.PushConfig)
+		}
+
+		// APIキー管理
+		keys := apiGroup.Group("/keys", auth.RequireRoles(domain.RoleOps))
+		{
+			keys.GET("", apiKeyHandler.List)
+			keys.POST("", apiKeyHandler.Create)
+			keys.POST("/:id/rotate", apiKeyHandler.Rotate)
+			keys.DELETE("/:id", apiKeyHandler.Revoke)
+		}
+
+		// 監査ログ
+		apiGroup.GET("/audit-events", auth.RequireRoles(domain.RoleOps), auditHandler.List)
 	}
 
 	// ダウンロードエンドポイント
 	r.GET("/download/:token", artworkHandler.Download)
+	// いいね数はleast_likedの退場順を決めるので、IPごとに制限し、同じ作品へは1日1回にする
+	r.POST("/download/:token/like", rateLimit.Limit("like", likeRateIP, repo.RateLimit{}), rateLimit.OncePerClient("like", "token", likeDedupTTL), artworkHandler.Like)
 
 	// WebSocketエンドポイント
-	r.GET("/ws", func(c *gin.Context) {
-		ws.ServeWS(hub, c.Writer, c.Request)
+	r.GET("/ws", auth.RequireRoles(domain.RoleDisplay), func(c *gin.Context) {
+		ws.ServeWS(hub, c.Writer, c.Request, api.CurrentAPIKey(c).ID)
 	})
 
 	// ヘルスチェック
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,25 @@ type Config struct {
 	UploadAPIKey string
 	DisplayAPIKey string
 	OpsAPIKey    string
+	// WebSocket配信バックエンド: local（単一インスタンス）または redis（pub/sub）
+	BroadcastBackend string
+	// X-Forwarded-Forを信用するプロキシ（ngrokやリバースプロキシのアドレス・CIDR）
+	// ここにないピアから来たX-Forwarded-Forは無視し、接続元アドレスをクライアントIPとする
+	TrustedProxies []string
+	// アップロードのレート制限（1分あたりの回数と連続で送れる回数、0なら制限なし）
+	UploadRatePerMinuteIP  int
+	UploadBurstIP          int
+	UploadRatePerMinuteKey int
+	UploadBurstKey         int
+	// いいねのレート制限（IPごと）
+	LikeRatePerMinuteIP int
+	LikeBurstIP         int
+	// アップロード画像の上限（バイト数、デコード後のピクセル数、許可するMIMEタイプ）
+	UploadMaxBytes     int64
+	UploadMaxPixels    int64
+	UploadAllowedMimes []string
+	// 監査ログの保存日数（0なら削除しない）
+	AuditRetentionDays int
 }
 
 func Load() *Config {
@@ -31,6 +52,20 @@ func Load() *Config {
 		UploadAPIKey: getEnv("UPLOAD_API_KEY", "upload_dev_key_12345"),
 		DisplayAPIKey: getEnv("DISPLAY_API_KEY", "display_dev_key_12345"),
 		OpsAPIKey:    getEnv("OPS_API_KEY", "ops_dev_key_12345"),
+		BroadcastBackend: getEnv("BROADCAST_BACKEND", "local"),
+		// 既定はローカルのプロキシとdocker-composeのフロントのnginx（172.28.1.0/24、ゲートウェイは含まない）
+		TrustedProxies:   getEnvList("TRUSTED_PROXIES", "127.0.0.1,::1,172.28.1.0/24"),
+		// アップロードページは全員が同じupload用キーを使うので、キーごとの上限は大きめ
+		UploadRatePerMinuteIP:  getEnvInt("UPLOAD_RATE_PER_MINUTE_IP", 6),
+		UploadBurstIP:          getEnvInt("UPLOAD_BURST_IP", 3),
+		UploadRatePerMinuteKey: getEnvInt("UPLOAD_RATE_PER_MINUTE_KEY", 60),
+		UploadBurstKey:         getEnvInt("UPLOAD_BURST_KEY", 20),
+		LikeRatePerMinuteIP:    getEnvInt("LIKE_RATE_PER_MINUTE_IP", 30),
+		LikeBurstIP:            getEnvInt("LIKE_BURST_IP", 10),
+		UploadMaxBytes:         int64(getEnvInt("UPLOAD_MAX_BYTES", 3*1024*1024)),
+		UploadMaxPixels:        int64(getEnvInt("UPLOAD_MAX_PIXELS", 4096*4096)),
+		UploadAllowedMimes:     getEnvList("UPLOAD_ALLOWED_MIMES", "image/png,image/jpeg,image/gif"),
+		AuditRetentionDays:     getEnvInt("AUDIT_RETENTION_DAYS", 90),
 	}
 }
 
@@ -40,3 +75,26 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/repo"
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// アニメーション種類の名前（フロントエンドのswitchで使う識別子）
+var animationKindNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+type AnimationKindHandler struct {
+	kindRepo *repo.AnimationKindRepository
+}
+
+func NewAnimationKindHandler(kindRepo *repo.AnimationKindRepository) *AnimationKindHandler {
+	return &AnimationKindHandler{
+		kindRepo: kindRepo,
+	}
+}
+
+type CreateAnimationKindRequest struct {
+	Name          string           `json:"name" binding:"required"`
+	Description   string           `json:"description"`
+	ParamSchema   *json.RawMessage `json:"param_schema"`
+	DefaultParams *json.RawMessage `json:"default_params"`
+}
+
+type UpdateAnimationKindRequest struct {
+	Description   *string          `json:"description"`
+	ParamSchema   *json.RawMessage `json:"param_schema"`
+	DefaultParams *json.RawMessage `json:"default_params"`
+	Enabled       *bool            `json:"enabled"`
+}
+
+// List はアニメーション種類の一覧を返す（?include_disabled=trueで無効化されたものも含める）
+func (h *AnimationKindHandler) List(c *gin.Context) {
+	kinds, err := h.kindRepo.List(c.Query("include_disabled") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get animation kinds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, kinds)
+}
+
+func (h *AnimationKindHandler) Create(c *gin.Context) {
+	var req CreateAnimationKindRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !animationKindNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid animation kind name"})
+		return
+	}
+
+	if _, err := h.kindRepo.GetByName(req.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Animation kind already exists"})
+		return
+	}
+
+	kind := &domain.AnimationKind{
+		Name:          req.Name,
+		Description:   req.Description,
+		ParamSchema:   jsonOrEmptyObject(req.ParamSchema),
+		DefaultParams: jsonOrEmptyObject(req.DefaultParams),
+		Enabled:       true,
+	}
+
+	if err := repo.ValidateAnimationParamSchema(kind.ParamSchema, kind.DefaultParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.kindRepo.Create(kind); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create animation kind"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, kind)
+}
+
+// Update はスキーマやデフォルト値を変更する。使用中の種類があるため削除ではなくenabled=falseで無効化する
+func (h *AnimationKindHandler) Update(c *gin.Context) {
+	kind, err := h.kindRepo.GetByName(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Animation kind not found"})
+		return
+	}
+
+	var req UpdateAnimationKindRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Description != nil {
+		kind.Description = *req.Description
+	}
+	if req.ParamSchema != nil {
+		kind.ParamSchema = req.ParamSchema
+	}
+	if req.DefaultParams != nil {
+		kind.DefaultParams = req.DefaultParams
+	}
+	if req.Enabled != nil {
+		kind.Enabled = *req.Enabled
+	}
+
+	if err := repo.ValidateAnimationParamSchema(kind.ParamSchema, kind.DefaultParams); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.kindRepo.Update(kind); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update animation kind"})
+		return
+	}
+
+	c.JSON(http.StatusOK, kind)
+}
+
+func jsonOrEmptyObject(raw *json.RawMessage) *json.RawMessage {
+	if raw == nil || len(*raw) == 0 || string(*raw) == "null" {
+		empty := json.RawMessage("{}")
+		return &empty
+	}
+	return raw
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/repo"
+	"culture-festival-backend/internal/ws"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyRepo *repo.APIKeyRepository
+	hub        *ws.Hub
+}
+
+func NewAPIKeyHandler(apiKeyRepo *repo.APIKeyRepository, hub *ws.Hub) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyRepo: apiKeyRepo,
+		hub:        hub,
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+// APIKeyResponse は一覧表示用にトークンを伏せたAPIキー
+type APIKeyResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Role        string     `json:"role"`
+	TokenPrefix string     `json:"token_prefix"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (h *APIKeyHandler) List(c *gin.Context) {
+	role := c.Query("role")
+	if role != "" && !isValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+	includeRevoked := c.Query("include_revoked") == "true"
+
+	apiKeys, err := h.apiKeyRepo.List(role, includeRevoked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API keys"})
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, toAPIKeyResponse(apiKey))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	token, err := repo.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	apiKey := &domain.APIKey{
+		Name:  req.Name,
+		Token: token,
+		Role:  req.Role,
+	}
+
+	if err := h.apiKeyRepo.Create(apiKey); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	addAuditTarget(c, "key_id", apiKey.ID)
+
+	// トークン全文を返すのは作成時とローテーション時のみ
+	c.JSON(http.StatusOK, apiKey)
+}
+
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	apiKey, ok := h.loadActiveKey(c)
+	if !ok {
+		return
+	}
+
+	if !h.ensureNotLastOpsKey(c, apiKey) {
+		return
+	}
+
+	if err := h.apiKeyRepo.Revoke(apiKey.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	// 接続中のWebSocketクライアントも即座に切断
+	// 複数インスタンス構成では全体の切断数が分からないのでnull
+	disconnected := h.hub.DisconnectAPIKey(apiKey.ID)
+	fmt.Printf("API key revoked: id=%d, disconnected_clients=%s\n", apiKey.ID, formatClientCount(disconnected))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "API key revoked successfully",
+		"disconnected_clients": disconnected,
+	})
+}
+
+func (h *APIKeyHandler) Rotate(c *gin.Context) {
+	apiKey, ok := h.loadActiveKey(c)
+	if !ok {
+		return
+	}
+
+	newKey, err := h.apiKeyRepo.Rotate(apiKey.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate API key"})
+		return
+	}
+
+	disconnected := h.hub.DisconnectAPIKey(apiKey.ID)
+	fmt.Printf("API key rotated: old_id=%d, new_id=%d, disconnected_clients=%s\n", apiKey.ID, newKey.ID, formatClientCount(disconnected))
+	addAuditTarget(c, "key_id", newKey.ID)
+
+	c.JSON(http.StatusOK, newKey)
+}
+
+func (h *APIKeyHandler) loadActiveKey(c *gin.Context) (*domain.APIKey, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return nil, false
+	}
+
+	apiKey, err := h.apiKeyRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return nil, false
+	}
+
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "API key already revoked"})
+		return nil, false
+	}
+
+	return apiKey, true
+}
+
+// ensureNotLastOpsKey は最後の有効なopsキーを失効させて締め出されるのを防ぐ
+func (h *APIKeyHandler) ensureNotLastOpsKey(c *gin.Context, apiKey *domain.APIKey) bool {
+	if apiKey.Role != domain.RoleOps {
+		return true
+	}
+
+	count, err := h.apiKeyRepo.CountActiveByRole(domain.RoleOps)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API keys"})
+		return false
+	}
+
+	if count <= 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot revoke the last active ops key"})
+		return false
+	}
+
+	return true
+}
+
+func toAPIKeyResponse(apiKey domain.APIKey) APIKeyResponse {
+	token := strings.TrimSpace(apiKey.Token)
+	prefix := token
+	if len(prefix) > 6 {
+		prefix = prefix[:6]
+	}
+
+	return APIKeyResponse{
+		ID:          apiKey.ID,
+		Name:        apiKey.Name,
+		Role:        apiKey.Role,
+		TokenPrefix: prefix,
+		RevokedAt:   apiKey.RevokedAt,
+		CreatedAt:   apiKey.CreatedAt,
+	}
+}
+
+func isValidRole(role string) bool {
+	return containsRole([]string{domain.RoleUpload, domain.RoleDisplay, domain.RoleOps}, role)
+}
+
+// formatClientCount はログ用にクライアント数を表示する（nilは他のインスタンスを含めた数が不明）
+func formatClientCount(count *int) string {
+	if count == nil {
+		return "unknown"
+	}
+	return strconv.Itoa(*count)
+}
@@ -6,6 +6,7 @@ import (
 	"culture-festival-backend/internal/storage"
 	"culture-festival-backend/internal/ws"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -16,13 +17,22 @@ import (
 	"github.com/google/uuid"
 )
 
+// multipartのうち画像以外（タイトル・タグ・境界など）に許す大きさ
+const uploadFormOverheadBytes = 1 << 20
+
+// 画像以外の理由でアップロードを拒否したときのコード（画像のコードはstorage.UploadError*）
+const uploadErrorMissingFile = "missing_file"
+
 type ArtworkHandler struct {
-	artworkRepo *repo.ArtworkRepository
-	assetRepo   *repo.AssetRepository
-	sceneRepo   *repo.SceneRepository
-	entityRepo  *repo.SceneEntityRepository
-	imageProc   *storage.ImageProcessor
-	hub         *ws.Hub
+	artworkRepo    *repo.ArtworkRepository
+	assetRepo      *repo.AssetRepository
+	sceneRepo      *repo.SceneRepository
+	entityRepo     *repo.SceneEntityRepository
+	imageProc      *storage.ImageProcessor
+	assigner       *repo.AnimationAssigner
+	moderationRepo *repo.ModerationRepository
+	takedownRepo   *repo.TakedownRepository
+	hub            *ws.Hub
 }
 
 func NewArtworkHandler(
@@ -31,15 +41,21 @@ func NewArtworkHandler(
 	sceneRepo *repo.SceneRepository,
 	entityRepo *repo.SceneEntityRepository,
 	imageProc *storage.ImageProcessor,
+	assigner *repo.AnimationAssigner,
+	moderationRepo *repo.ModerationRepository,
+	takedownRepo *repo.TakedownRepository,
 	hub *ws.Hub,
 ) *ArtworkHandler {
 	return &ArtworkHandler{
-		artworkRepo: artworkRepo,
-		assetRepo:   assetRepo,
-		sceneRepo:   sceneRepo,
-		entityRepo:  entityRepo,
-		imageProc:   imageProc,
-		hub:         hub,
+		artworkRepo:    artworkRepo,
+		assetRepo:      assetRepo,
+		sceneRepo:      sceneRepo,
+		entityRepo:     entityRepo,
+		imageProc:      imageProc,
+		assigner:       assigner,
+		moderationRepo: moderationRepo,
+		takedownRepo:   takedownRepo,
+		hub:            hub,
 	}
 }
 
@@ -53,13 +69,38 @@ type UploadResponse struct {
 	AssetURL  string `json:"asset_url"`
 	ThumbURL  string `json:"thumb_url"`
 	QRToken   string `json:"qr_token"`
+	SceneIDs  []uint `json:"scene_ids"`
+	EntityIDs []uint `json:"entity_ids"`
+	// 承認制のシーンには承認されてから追加される
+	Status          string `json:"status"`
+	PendingSceneIDs []uint `json:"pending_scene_ids"`
+	// 追加に失敗したシーン（アートワーク自体は保存済みなので再送しないこと）
+	FailedScenes []UploadSceneError `json:"failed_scenes,omitempty"`
+}
+
+type UploadSceneError struct {
+	SceneID uint   `json:"scene_id"`
+	Error   string `json:"error"`
 }
 
 func (h *ArtworkHandler) Upload(c *gin.Context) {
+	// 上限を大きく超えるボディは一時ファイルにも書かずに打ち切る
+	if maxBytes := h.imageProc.Limits.MaxBytes; maxBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+uploadFormOverheadBytes)
+	}
+
 	// ファイルを取得
 	file, header, err := c.Request.FormFile("image")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided"})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondUploadError(c, &storage.UploadError{
+				Code:    storage.UploadErrorFileTooLarge,
+				Message: fmt.Sprintf("file exceeds %d bytes", h.imageProc.Limits.MaxBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No image file provided", "code": uploadErrorMissingFile})
 		return
 	}
 	defer file.Close()
@@ -68,10 +109,44 @@ func (h *ArtworkHandler) Upload(c *gin.Context) {
 	title := c.PostForm("title")
 	tags := c.PostForm("tags")
 
+	// 振り分け先のシーンを決める（画像処理の前に検証する）
+	sceneIDs, err := parseSceneIDs(c.PostFormArray("scene_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var scenes []domain.Scene
+	if len(sceneIDs) > 0 {
+		for _, id := range sceneIDs {
+			scene, err := h.sceneRepo.GetBasicByID(id)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Scene not found: %d", id)})
+				return
+			}
+			scenes = append(scenes, *scene)
+		}
+	} else {
+		scenes, err = h.sceneRepo.ListUploadTargets()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load upload scenes"})
+			return
+		}
+		if len(scenes) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "No upload scene configured"})
+			return
+		}
+	}
+
 	// 画像を処理
 	processedImg, err := h.imageProc.ProcessUpload(file, header)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to process image: %v", err)})
+		var uploadErr *storage.UploadError
+		if errors.As(err, &uploadErr) {
+			respondUploadError(c, uploadErr)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process image: %v", err)})
 		return
 	}
 
@@ -111,6 +186,16 @@ func (h *ArtworkHandler) Upload(c *gin.Context) {
 		tagsJSON = json.RawMessage("[]")
 	}
 
+	// 承認制のシーンが1つでもあれば審査待ちにする
+	status := domain.ArtworkStatusApproved
+	var pendingSceneIDs []uint
+	for _, scene := range scenes {
+		if scene.RequireApproval {
+			status = domain.ArtworkStatusPending
+			pendingSceneIDs = append(pendingSceneIDs, scene.ID)
+		}
+	}
+
 	// アートワークを保存
 	artwork := &domain.Artwork{
 		AssetID:   asset.ID,
@@ -118,6 +203,7 @@ func (h *ArtworkHandler) Upload(c *gin.Context) {
 		Tags:      &tagsJSON,
 		QRToken:   qrToken,
 		ThumbPath: processedImg.ThumbPath,
+		Status:    status,
 	}
 
 	if err := h.artworkRepo.Create(artwork); err != nil {
@@ -125,43 +211,64 @@ func (h *ArtworkHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	// デフォルトシーン（ID=1）にエンティティを追加
-	sceneID := uint(1)
-	x, y, vx, vy := repo.GenerateRandomPositionAndVelocity(1920, 1080) // デフォルトサイズ
-
-	entity := &domain.SceneEntity{
-		SceneID:       sceneID,
-		ArtworkID:     artwork.ID,
-		InitX:         x,
-		InitY:         y,
-		InitVX:        vx,
-		InitVY:        vy,
-		InitAngle:     0,
-		InitScale:     0.25,
-		AnimationKind: repo.GetNextAnimationKind(),
-		RNGSeed:       repo.GetRandomRNGSeed(),
-	}
-
-	if err := h.entityRepo.Create(entity); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add entity to scene"})
+	if err := h.moderationRepo.AddPending(artwork.ID, pendingSceneIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue artwork for approval"})
 		return
 	}
 
-	// WebSocketでブロードキャスト
-	fmt.Printf("Broadcasting entity add: entity_id=%d, scene_id=%d\n", entity.ID, entity.SceneID)
-	h.broadcastEntityAdd(entity, artwork, asset)
-
-	// レスポンスを返す
+	// 承認制でないシーンにはすぐエンティティを追加（位置はシーンの実サイズ基準）
+	// 追加済みのシーンには配信も済んでいるので、一部のシーンで失敗しても200で成功したシーンと失敗したシーンを返す
 	response := UploadResponse{
-		ArtworkID: artwork.ID,
-		AssetURL:  fmt.Sprintf("/download/%s", qrToken),
-		ThumbURL:  fmt.Sprintf("/download/%s?thumb=true", qrToken),
-		QRToken:   qrToken,
+		ArtworkID:       artwork.ID,
+		AssetURL:        fmt.Sprintf("/download/%s", qrToken),
+		ThumbURL:        fmt.Sprintf("/download/%s?thumb=true", qrToken),
+		QRToken:         qrToken,
+		Status:          status,
+		PendingSceneIDs: pendingSceneIDs,
 	}
 
+	for i := range scenes {
+		scene := &scenes[i]
+		if scene.RequireApproval {
+			continue
+		}
+
+		entity, err := h.placeArtwork(scene, artwork)
+		if err != nil {
+			fmt.Printf("Failed to add entity to scene: artwork_id=%d, scene_id=%d, error=%v\n", artwork.ID, scene.ID, err)
+			response.FailedScenes = append(response.FailedScenes, UploadSceneError{
+				SceneID: scene.ID,
+				Error:   "Failed to add entity to scene",
+			})
+			continue
+		}
+
+		response.SceneIDs = append(response.SceneIDs, scene.ID)
+		response.EntityIDs = append(response.EntityIDs, entity.ID)
+	}
+
+	addAuditTarget(c, "artwork_id", artwork.ID)
+	for _, entityID := range response.EntityIDs {
+		addAuditTarget(c, "entity_id", entityID)
+	}
+
+	// レスポンスを返す
+
 	c.JSON(http.StatusOK, response)
 }
 
+// respondUploadError は受け付けられない画像の理由をコード付きで返す
+func respondUploadError(c *gin.Context, err *storage.UploadError) {
+	status := http.StatusBadRequest
+	switch err.Code {
+	case storage.UploadErrorFileTooLarge, storage.UploadErrorImageTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case storage.UploadErrorUnsupportedType:
+		status = http.StatusUnsupportedMediaType
+	}
+	c.JSON(status, gin.H{"error": err.Message, "code": err.Code})
+}
+
 func (h *ArtworkHandler) GetByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -171,7 +278,7 @@ func (h *ArtworkHandler) GetByID(c *gin.Context) {
 	}
 
 	artwork, err := h.artworkRepo.GetByID(uint(id))
-	if err != nil {
+	if err != nil || artwork.TakenDownAt != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
 		return
 	}
@@ -207,7 +314,7 @@ func (h *ArtworkHandler) Delete(c *gin.Context) {
 	}
 
 	// 関連するシーンエンティティを削除
-	if err := h.entityRepo.DeleteByArtworkID(artworkID); err != nil {
+	if err := h.removeFromScenes(artworkID, ws.RemoveReasonArtworkDeleted); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete related entities"})
 		return
 	}
@@ -218,9 +325,6 @@ func (h *ArtworkHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	// WebSocketでブロードキャスト（エンティティ削除を通知）
-	h.broadcastEntityDelete(artworkID)
-
 	c.JSON(http.StatusOK, gin.H{"message": "Artwork and related entities deleted successfully"})
 }
 
@@ -237,6 +341,13 @@ func (h *ArtworkHandler) Download(c *gin.Context) {
 		return
 	}
 
+	// 却下・取り下げ中の作品は存在しないものとして扱う
+	if artwork.Status == domain.ArtworkStatusRejected || artwork.TakenDownAt != nil {
+		fmt.Printf("Artwork hidden: token=%s, status=%s\n", token, artwork.Status)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
+		return
+	}
+
 	fmt.Printf("Artwork found: id=%d, thumb_path=%s\n", artwork.ID, artwork.ThumbPath)
 
 	var filePath string
@@ -256,41 +367,108 @@ func (h *ArtworkHandler) Download(c *gin.Context) {
 	c.File(filePath)
 }
 
-func (h *ArtworkHandler) broadcastEntityAdd(entity *domain.SceneEntity, artwork *domain.Artwork, asset *domain.Asset) {
-	room := fmt.Sprintf("scene:%d", entity.SceneID)
+// Like はQRコードから開いた来場者のいいねを記録する（least_likedの退場順に使う）
+func (h *ArtworkHandler) Like(c *gin.Context) {
+	token := c.Param("token")
 
-	message := ws.Message{
-		Type: "entity.add",
-		Data: map[string]interface{}{
-			"entity_id":   entity.ID,
-			"artwork_id":  artwork.ID, // 作品IDを追加
-			"artwork_url": fmt.Sprintf("/download/%s", artwork.QRToken),
-			"init": map[string]interface{}{
-				"x":     entity.InitX,
-				"y":     entity.InitY,
-				"vx":    entity.InitVX,
-				"vy":    entity.InitVY,
-				"angle": entity.InitAngle,
-				"scale": entity.InitScale,
-			},
-			"animation_kind": entity.AnimationKind,
-			"seed":           entity.RNGSeed,
-		},
-	}
-
-	fmt.Printf("Broadcasting to room: %s\n", room)
-	h.hub.BroadcastToRoom(room, message)
+	likeCount, err := h.artworkRepo.Like(token)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"like_count": likeCount})
 }
 
-func (h *ArtworkHandler) broadcastEntityDelete(artworkID uint) {
-	// 全シーンにエンティティ削除を通知
+// parseSceneIDs はscene_idフォーム値（複数指定・カンマ区切りどちらも可）を重複なく解釈する
+func parseSceneIDs(values []string) ([]uint, error) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid scene ID: %s", s)
+			}
+			if !seen[uint(id)] {
+				seen[uint(id)] = true
+				ids = append(ids, uint(id))
+			}
+		}
+	}
+	return ids, nil
+}
+
+// placeArtwork はシーンの実サイズ基準のランダムな位置にエンティティを追加して配信する
+func (h *ArtworkHandler) placeArtwork(scene *domain.Scene, artwork *domain.Artwork) (*domain.SceneEntity, error) {
+	x, y, vx, vy := repo.GenerateRandomPositionAndVelocity(scene.Width, scene.Height)
+
+	entity := &domain.SceneEntity{
+		SceneID:   scene.ID,
+		ArtworkID: artwork.ID,
+		InitX:     x,
+		InitY:     y,
+		InitVX:    vx,
+		InitVY:    vy,
+		InitAngle: 0,
+		InitScale: 0.25,
+		RNGSeed:   repo.GetRandomRNGSeed(),
+	}
+
+	// アニメーション種類はシーンのポリシーで決め、シーン単位のロック内で作成する
+	if err := h.assigner.Place(scene, entity, nil); err != nil {
+		return nil, err
+	}
+
+	// WebSocketでブロードキャスト
+	fmt.Printf("Broadcasting entity add: entity_id=%d, scene_id=%d\n", entity.ID, entity.SceneID)
+	h.broadcastEntityAdd(entity, artwork)
+	// 定員を超えたら今回の作品以外を退場させる
+	h.hub.EnforceCapacity(scene.ID, entity.ID)
+
+	return entity, nil
+}
+
+// removeFromScenes はアートワークのエンティティを全シーンから削除し、含んでいたシーンにのみ通知する
+func (h *ArtworkHandler) removeFromScenes(artworkID uint, reason string) error {
+	// 削除通知を送るシーンを特定するため、先に関連エンティティを取得
+	entities, err := h.entityRepo.GetByArtworkID(artworkID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.entityRepo.DeleteByArtworkID(artworkID); err != nil {
+		return err
+	}
+
+	sceneIDs := make(map[uint]bool)
+	for i := range entities {
+		h.hub.PublishEntityRemove(&entities[i], reason)
+		sceneIDs[entities[i].SceneID] = true
+	}
+	// 空いた枠にrotateのキューから戻す
+	for sceneID := range sceneIDs {
+		h.hub.EnforceCapacity(sceneID)
+	}
+	return nil
+}
+
+func (h *ArtworkHandler) broadcastEntityAdd(entity *domain.SceneEntity, artwork *domain.Artwork) {
+	entity.Artwork = *artwork
+
 	message := ws.Message{
-		Type: "entity.delete",
-		Data: map[string]interface{}{
-			"artwork_id": artworkID,
-		},
+		Type: "entity.add",
+		Data: ws.EntityPayload(entity),
 	}
 
-	// 全シーンにブロードキャスト
-	h.hub.BroadcastToAll(message)
+	fmt.Printf("Broadcasting to room: %s\n", ws.SceneRoom(entity.SceneID))
+	h.hub.PublishSceneEvent(entity.SceneID, message)
+
+	if entity.AnimationKind == "spin_fight" {
+		h.hub.ReconcileFights(entity.SceneID)
+	}
 }
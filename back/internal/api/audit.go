@@ -0,0 +1,357 @@
+package api
+
+import (
+	"bytes"
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/repo"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 要約に残すリクエストボディの上限
+	maxAuditBodyBytes = 2048
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// ハンドラーが作成したリソースのID（addAuditTarget）
+	auditTargetsContextKey = "audit_targets"
+	// 保存期間を過ぎたイベントを削除する間隔
+	auditPurgeInterval = time.Hour
+)
+
+type AuditMiddleware struct {
+	auditRepo *repo.AuditRepository
+}
+
+func NewAuditMiddleware(auditRepo *repo.AuditRepository) *AuditMiddleware {
+	return &AuditMiddleware{auditRepo: auditRepo}
+}
+
+// Record は認証を通った状態を変更するリクエスト（POST/PUT/PATCH/DELETE）をaudit_eventsに記録する
+// 認証はルートごとのミドルウェアで行われるので、APIキーはハンドラーの実行後に読む
+// 認証のないルート（いいね）・認証で拒否されたもの・レート制限で拒否されたものは誰でも大量に
+// 送れてテーブルを埋められるので記録しない
+func (m *AuditMiddleware) Record() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		body := peekRequestBody(c.Request)
+
+		c.Next()
+
+		apiKey := CurrentAPIKey(c)
+		if apiKey == nil || c.Writer.Status() == http.StatusTooManyRequests {
+			return
+		}
+
+		targets, err := json.Marshal(auditTargets(c, body))
+		if err != nil {
+			targets = []byte("{}")
+		}
+		targetsJSON := json.RawMessage(targets)
+
+		route := c.FullPath()
+		if route == "" {
+			// 存在しないルート
+			route = c.Request.URL.Path
+		}
+
+		event := &domain.AuditEvent{
+			Method:     c.Request.Method,
+			Route:      route,
+			Path:       truncate(c.Request.URL.Path, 500),
+			Targets:    &targetsJSON,
+			Summary:    auditSummary(c, body),
+			Status:     c.Writer.Status(),
+			DurationMS: time.Since(start).Milliseconds(),
+			ClientIP:   c.ClientIP(),
+			APIKeyID:   &apiKey.ID,
+			APIKeyName: &apiKey.Name,
+			Role:       &apiKey.Role,
+		}
+
+		if err := m.auditRepo.Create(event); err != nil {
+			fmt.Printf("Failed to record audit event: %s %s, error=%v\n", event.Method, event.Path, err)
+		}
+	}
+}
+
+// RunRetention はretentionより古いイベントを定期的に削除する（retentionが0以下なら何もしない）
+// 複数インスタンスで動いても同じ条件で削除するだけなので問題ない
+func (m *AuditMiddleware) RunRetention(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(auditPurgeInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := m.auditRepo.DeleteBefore(time.Now().Add(-retention))
+		if err != nil {
+			fmt.Printf("Failed to purge audit events: error=%v\n", err)
+		} else if deleted > 0 {
+			fmt.Printf("Purged %d audit events older than %s\n", deleted, retention)
+		}
+		<-ticker.C
+	}
+}
+
+// addAuditTarget はハンドラーが作成したリソースのIDを監査ログの対象に加える
+func addAuditTarget(c *gin.Context, key string, id uint) {
+	targets, _ := c.Get(auditTargetsContextKey)
+	m, _ := targets.(map[string][]interface{})
+	if m == nil {
+		m = make(map[string][]interface{})
+	}
+	m[key] = appendTarget(m[key], id)
+	c.Set(auditTargetsContextKey, m)
+}
+
+// peekRequestBody はJSONなどのボディを先頭だけ読み、ハンドラーが最初から読めるよう戻す
+// multipartは画像を含むので読まない（要約はハンドラーが解析したフォームから作る）
+func peekRequestBody(req *http.Request) []byte {
+	if req.Body == nil || strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		return nil
+	}
+
+	buf, _ := io.ReadAll(io.LimitReader(req.Body, maxAuditBodyBytes+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+	return buf
+}
+
+// auditTargets はパスパラメータとボディの*_id / *_idsから操作対象を集める
+// :idはひとつ前のセグメントから名前を付ける（/api/scenes/:id → scene_id）
+func auditTargets(c *gin.Context, body []byte) map[string][]interface{} {
+	targets := make(map[string][]interface{})
+
+	segments := strings.Split(c.FullPath(), "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		name := segment[1:]
+		key := name
+		if name == "id" && i > 0 {
+			key = strings.TrimSuffix(strings.ReplaceAll(segments[i-1], "-", "_"), "s") + "_id"
+		}
+		targets[key] = appendTarget(targets[key], targetValue(c.Param(name)))
+	}
+
+	var fields map[string]interface{}
+	if len(body) <= maxAuditBodyBytes && json.Unmarshal(body, &fields) == nil {
+		for key, value := range fields {
+			switch {
+			case strings.HasSuffix(key, "_id"):
+				if n, ok := value.(float64); ok {
+					targets[key] = appendTarget(targets[key], n)
+				}
+			case strings.HasSuffix(key, "_ids"):
+				values, _ := value.([]interface{})
+				single := strings.TrimSuffix(key, "s")
+				for _, v := range values {
+					if n, ok := v.(float64); ok {
+						targets[single] = appendTarget(targets[single], n)
+					}
+				}
+			}
+		}
+	}
+
+	if form := c.Request.MultipartForm; form != nil {
+		for _, value := range form.Value["scene_id"] {
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					targets["scene_id"] = appendTarget(targets["scene_id"], targetValue(s))
+				}
+			}
+		}
+	}
+
+	if created, ok := c.Get(auditTargetsContextKey); ok {
+		for key, ids := range created.(map[string][]interface{}) {
+			for _, id := range ids {
+				targets[key] = appendTarget(targets[key], id)
+			}
+		}
+	}
+	return targets
+}
+
+// targetValue は数値のIDを数値として保存する（@>での検索で型を揃えるため）
+func targetValue(s string) interface{} {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n
+	}
+	return s
+}
+
+func appendTarget(values []interface{}, value interface{}) []interface{} {
+	for _, v := range values {
+		if fmt.Sprint(v) == fmt.Sprint(value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// auditSummary はクエリとボディ（multipartならフォーム値とファイル名・サイズ）を1行にまとめる
+func auditSummary(c *gin.Context, body []byte) string {
+	var parts []string
+	if query := redactedQuery(c.Request.URL); query != "" {
+		parts = append(parts, "?"+query)
+	}
+
+	if form := c.Request.MultipartForm; form != nil {
+		keys := make([]string, 0, len(form.Value))
+		for key := range form.Value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s=%s", key, strings.Join(form.Value[key], ",")))
+		}
+		for key, files := range form.File {
+			for _, file := range files {
+				parts = append(parts, fmt.Sprintf("%s=%s(%dB)", key, file.Filename, file.Size))
+			}
+		}
+	} else if len(body) > 0 {
+		var compacted bytes.Buffer
+		if len(body) <= maxAuditBodyBytes && json.Compact(&compacted, body) == nil {
+			parts = append(parts, compacted.String())
+		} else {
+			parts = append(parts, truncate(string(body), maxAuditBodyBytes)+"...")
+		}
+	}
+
+	return truncate(strings.Join(parts, " "), maxAuditBodyBytes)
+}
+
+// redactedQuery はクエリからAPIキー（extractAPIKeyが?api_key=でも受け付ける）を除く
+func redactedQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		// 解析できないクエリは秘密を含むかもしれないので残さない
+		return "(unparsable query)"
+	}
+	query.Del("api_key")
+	return query.Encode()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// UTF-8の途中で切らない
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+type AuditHandler struct {
+	auditRepo *repo.AuditRepository
+}
+
+func NewAuditHandler(auditRepo *repo.AuditRepository) *AuditHandler {
+	return &AuditHandler{auditRepo: auditRepo}
+}
+
+// List は監査ログを新しい順に返す
+// ?from=&to=（RFC3339）, ?actor=（キー名またはID）, ?role=, ?method=, ?status=,
+// ?target=scene_id:3（複数指定はすべて含むもの）, ?before_id=, ?limit=
+func (h *AuditHandler) List(c *gin.Context) {
+	filter := repo.AuditFilter{
+		Actor:  c.Query("actor"),
+		Role:   c.Query("role"),
+		Method: strings.ToUpper(c.Query("method")),
+		Limit:  defaultAuditLimit,
+	}
+
+	for _, param := range []string{"from", "to"} {
+		s := c.Query(param)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s (RFC3339 expected)", param)})
+			return
+		}
+		// created_atはタイムゾーンなしで保存されているのでローカル時刻に揃える
+		t = t.Local()
+		if param == "from" {
+			filter.From = &t
+		} else {
+			filter.To = &t
+		}
+	}
+
+	if s := c.Query("status"); s != "" {
+		status, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+		filter.Status = status
+	}
+
+	if s := c.Query("before_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before_id"})
+			return
+		}
+		filter.BeforeID = uint(id)
+	}
+
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	for _, target := range c.QueryArray("target") {
+		key, value, ok := strings.Cut(target, ":")
+		if !ok || key == "" || value == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target (key:value expected)"})
+			return
+		}
+		if filter.Targets == nil {
+			filter.Targets = make(map[string][]interface{})
+		}
+		filter.Targets[key] = appendTarget(filter.Targets[key], targetValue(value))
+	}
+
+	events, err := h.auditRepo.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
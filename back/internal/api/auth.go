@@ -0,0 +1,75 @@
+package api
+
+import (
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/repo"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiKeyContextKey = "api_key"
+
+type AuthMiddleware struct {
+	apiKeyRepo *repo.APIKeyRepository
+}
+
+func NewAuthMiddleware(apiKeyRepo *repo.APIKeyRepository) *AuthMiddleware {
+	return &AuthMiddleware{apiKeyRepo: apiKeyRepo}
+}
+
+// RequireRoles は指定したロールのいずれかを持つAPIキーのみ通過させる
+func (m *AuthMiddleware) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := extractAPIKey(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			return
+		}
+
+		apiKey, err := m.apiKeyRepo.GetByToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+
+		if !containsRole(roles, apiKey.Role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+
+		c.Set(apiKeyContextKey, apiKey)
+		c.Next()
+	}
+}
+
+// CurrentAPIKey は認証済みリクエストのAPIキーを返す（未認証ならnil）
+func CurrentAPIKey(c *gin.Context) *domain.APIKey {
+	value, exists := c.Get(apiKeyContextKey)
+	if !exists {
+		return nil
+	}
+	apiKey, _ := value.(*domain.APIKey)
+	return apiKey
+}
+
+func extractAPIKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return strings.TrimSpace(key)
+	}
+	// ブラウザのWebSocketはヘッダーを付けられないのでクエリでも受け付ける
+	return strings.TrimSpace(c.Query("api_key"))
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
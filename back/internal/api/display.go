@@ -0,0 +1,268 @@
+package api
+
+import (
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/repo"
+	"culture-festival-backend/internal/ws"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DisplayHandler struct {
+	displayRepo *repo.DisplayNodeRepository
+	sceneRepo   *repo.SceneRepository
+	hub         *ws.Hub
+}
+
+func NewDisplayHandler(
+	displayRepo *repo.DisplayNodeRepository,
+	sceneRepo *repo.SceneRepository,
+	hub *ws.Hub,
+) *DisplayHandler {
+	return &DisplayHandler{
+		displayRepo: displayRepo,
+		sceneRepo:   sceneRepo,
+		hub:         hub,
+	}
+}
+
+type CreateDisplayRequest struct {
+	SceneID     uint    `json:"scene_id" binding:"required"`
+	Name        string  `json:"name" binding:"required"`
+	ViewportX   int     `json:"viewport_x"`
+	ViewportY   int     `json:"viewport_y"`
+	ViewportW   int     `json:"viewport_w" binding:"required"`
+	ViewportH   int     `json:"viewport_h" binding:"required"`
+	Scale       float64 `json:"scale"`
+	PixelWidth  int     `json:"pixel_width" binding:"required"`
+	PixelHeight int     `json:"pixel_height" binding:"required"`
+}
+
+// UpdateDisplayRequest は指定されたフィールドのみ更新する
+type UpdateDisplayRequest struct {
+	SceneID     *uint    `json:"scene_id"`
+	Name        *string  `json:"name"`
+	ViewportX   *int     `json:"viewport_x"`
+	ViewportY   *int     `json:"viewport_y"`
+	ViewportW   *int     `json:"viewport_w"`
+	ViewportH   *int     `json:"viewport_h"`
+	Scale       *float64 `json:"scale"`
+	PixelWidth  *int     `json:"pixel_width"`
+	PixelHeight *int     `json:"pixel_height"`
+}
+
+func (h *DisplayHandler) Create(c *gin.Context) {
+	var req CreateDisplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Scale == 0 {
+		req.Scale = 1
+	}
+
+	if _, err := h.sceneRepo.GetBasicByID(req.SceneID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	deviceKey, err := repo.GenerateToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device key"})
+		return
+	}
+
+	node := &domain.DisplayNode{
+		SceneID:     &req.SceneID,
+		Name:        req.Name,
+		ViewportX:   req.ViewportX,
+		ViewportY:   req.ViewportY,
+		ViewportW:   req.ViewportW,
+		ViewportH:   req.ViewportH,
+		Scale:       req.Scale,
+		PixelWidth:  req.PixelWidth,
+		PixelHeight: req.PixelHeight,
+		DeviceKey:   deviceKey,
+	}
+
+	if msg := validateDisplayNode(node); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.displayRepo.Create(node); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create display"})
+		return
+	}
+	addAuditTarget(c, "display_id", node.ID)
+
+	c.JSON(http.StatusOK, node)
+}
+
+func (h *DisplayHandler) List(c *gin.Context) {
+	var (
+		nodes []domain.DisplayNode
+		err   error
+	)
+
+	if sceneIDStr := c.Query("scene_id"); sceneIDStr != "" {
+		sceneID, parseErr := strconv.ParseUint(sceneIDStr, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+			return
+		}
+		nodes, err = h.displayRepo.GetBySceneID(uint(sceneID))
+	} else {
+		nodes, err = h.displayRepo.List()
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get displays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, nodes)
+}
+
+func (h *DisplayHandler) GetByID(c *gin.Context) {
+	node, ok := h.loadDisplay(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, node)
+}
+
+func (h *DisplayHandler) Update(c *gin.Context) {
+	node, ok := h.loadDisplay(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateDisplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// 切り離されたノードもscene_idを指定すれば再割り当てできる
+	if req.SceneID != nil && (node.SceneID == nil || *req.SceneID != *node.SceneID) {
+		scene, err := h.sceneRepo.GetBasicByID(*req.SceneID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scene not found"})
+			return
+		}
+		node.SceneID = &scene.ID
+		node.Scene = scene
+	}
+	if req.Name != nil {
+		node.Name = *req.Name
+	}
+	if req.ViewportX != nil {
+		node.ViewportX = *req.ViewportX
+	}
+	if req.ViewportY != nil {
+		node.ViewportY = *req.ViewportY
+	}
+	if req.ViewportW != nil {
+		node.ViewportW = *req.ViewportW
+	}
+	if req.ViewportH != nil {
+		node.ViewportH = *req.ViewportH
+	}
+	if req.Scale != nil {
+		node.Scale = *req.Scale
+	}
+	if req.PixelWidth != nil {
+		node.PixelWidth = *req.PixelWidth
+	}
+	if req.PixelHeight != nil {
+		node.PixelHeight = *req.PixelHeight
+	}
+
+	if msg := validateDisplayNode(node); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := h.displayRepo.Update(node); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update display"})
+		return
+	}
+
+	// 接続中のディスプレイに再接続なしで反映
+	h.hub.ApplyDisplayConfig(node)
+
+	c.JSON(http.StatusOK, node)
+}
+
+func (h *DisplayHandler) Delete(c *gin.Context) {
+	node, ok := h.loadDisplay(c)
+	if !ok {
+		return
+	}
+
+	if err := h.displayRepo.Delete(node.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete display"})
+		return
+	}
+
+	h.hub.DisconnectDeviceKey(node.DeviceKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Display deleted successfully"})
+}
+
+// PushConfig は設定変更なしでdisplay.configを再送する（表示がずれた時の再同期用）
+func (h *DisplayHandler) PushConfig(c *gin.Context) {
+	node, ok := h.loadDisplay(c)
+	if !ok {
+		return
+	}
+
+	// 複数インスタンス構成では全体の更新数が分からないのでnull
+	updated := h.hub.ApplyDisplayConfig(node)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Display config pushed",
+		"updated_clients": updated,
+	})
+}
+
+func (h *DisplayHandler) loadDisplay(c *gin.Context) (*domain.DisplayNode, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid display ID"})
+		return nil, false
+	}
+
+	node, err := h.displayRepo.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Display not found"})
+		return nil, false
+	}
+
+	return node, true
+}
+
+func validateDisplayNode(node *domain.DisplayNode) string {
+	if node.Name == "" {
+		return "Name is required"
+	}
+	if node.ViewportW <= 0 || node.ViewportH <= 0 {
+		return "Viewport width and height must be positive"
+	}
+	if node.ViewportX < 0 || node.ViewportY < 0 {
+		return "Viewport position must not be negative"
+	}
+	if node.Scale <= 0 {
+		return "Scale must be positive"
+	}
+	if node.PixelWidth <= 0 || node.PixelHeight <= 0 {
+		return "Pixel width and height must be positive"
+	}
+	return ""
+}
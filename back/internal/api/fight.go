@@ -0,0 +1,55 @@
+package api
+
+import (
+	"culture-festival-backend/internal/repo"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+type FightHandler struct {
+	fightRepo *repo.FightRepository
+}
+
+func NewFightHandler(fightRepo *repo.FightRepository) *FightHandler {
+	return &FightHandler{
+		fightRepo: fightRepo,
+	}
+}
+
+// Leaderboard はspin_fightの勝利数ランキングを返す（?scene_id=で絞り込み、?limit=で件数指定）
+func (h *FightHandler) Leaderboard(c *gin.Context) {
+	var sceneID uint64
+	if s := c.Query("scene_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+			return
+		}
+		sceneID = id
+	}
+
+	limit := defaultLeaderboardLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.fightRepo.Leaderboard(uint(sceneID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get leaderboard"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
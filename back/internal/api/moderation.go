@@ -0,0 +1,154 @@
+package api
+
+import (
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/ws"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationRequest は複数のアートワークをまとめて承認・却下する
+type ModerationRequest struct {
+	ArtworkIDs []uint `json:"artwork_ids" binding:"required"`
+	// 却下では必須
+	Reason string `json:"reason"`
+}
+
+// 審査理由の最大長（artworks.moderation_reason）
+const maxModerationReasonLength = 500
+
+// ListPending は承認待ちのアートワークを古い順に返す（pending_placementsに承認後の追加先）
+func (h *ArtworkHandler) ListPending(c *gin.Context) {
+	artworks, err := h.moderationRepo.ListPending()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pending artworks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, artworks)
+}
+
+// Approve は承認待ちのアートワークを承認し、保留していたシーンに追加してentity.addを配信する
+func (h *ArtworkHandler) Approve(c *gin.Context) {
+	req, ok := bindModerationRequest(c, false)
+	if !ok {
+		return
+	}
+
+	result, err := h.moderationRepo.Decide(req.ArtworkIDs, domain.ArtworkStatusApproved, optionalReason(req.Reason), moderatorID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve artworks"})
+		return
+	}
+
+	entityIDs := make([]uint, 0, len(result.Placements))
+	for _, placement := range result.Placements {
+		scene, err := h.sceneRepo.GetBasicByID(placement.SceneID)
+		if err != nil {
+			fmt.Printf("Scene not found for approved artwork: artwork_id=%d, scene_id=%d\n", placement.ArtworkID, placement.SceneID)
+			continue
+		}
+		artwork, err := h.artworkRepo.GetByID(placement.ArtworkID)
+		if err != nil {
+			fmt.Printf("Approved artwork not found: artwork_id=%d\n", placement.ArtworkID)
+			continue
+		}
+
+		entity, err := h.placeArtwork(scene, artwork)
+		if err != nil {
+			fmt.Printf("Failed to add approved artwork: artwork_id=%d, scene_id=%d, error=%v\n", placement.ArtworkID, placement.SceneID, err)
+			continue
+		}
+		entityIDs = append(entityIDs, entity.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approved":   result.ArtworkIDs,
+		"skipped":    skippedArtworkIDs(req.ArtworkIDs, result.ArtworkIDs),
+		"entity_ids": entityIDs,
+	})
+}
+
+// Reject は承認待ちのアートワークを却下する
+// 承認制でないシーンに表示済みのエンティティも削除し、以後はダウンロードもできなくなる
+func (h *ArtworkHandler) Reject(c *gin.Context) {
+	req, ok := bindModerationRequest(c, true)
+	if !ok {
+		return
+	}
+
+	result, err := h.moderationRepo.Decide(req.ArtworkIDs, domain.ArtworkStatusRejected, optionalReason(req.Reason), moderatorID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject artworks"})
+		return
+	}
+
+	for _, artworkID := range result.ArtworkIDs {
+		if err := h.removeFromScenes(artworkID, ws.RemoveReasonRejected); err != nil {
+			fmt.Printf("Failed to remove rejected artwork from scenes: artwork_id=%d, error=%v\n", artworkID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rejected": result.ArtworkIDs,
+		"skipped":  skippedArtworkIDs(req.ArtworkIDs, result.ArtworkIDs),
+	})
+}
+
+func bindModerationRequest(c *gin.Context, requireReason bool) (*ModerationRequest, bool) {
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.ArtworkIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "artwork_ids must not be empty"})
+		return nil, false
+	}
+	if requireReason && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return nil, false
+	}
+	if len([]rune(req.Reason)) > maxModerationReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Reason must be at most %d characters", maxModerationReasonLength)})
+		return nil, false
+	}
+	return &req, true
+}
+
+func optionalReason(reason string) *string {
+	if reason == "" {
+		return nil
+	}
+	return &reason
+}
+
+// moderatorID は操作したAPIキーのID
+func moderatorID(c *gin.Context) *uint {
+	apiKey := CurrentAPIKey(c)
+	if apiKey == nil {
+		return nil
+	}
+	return &apiKey.ID
+}
+
+// skippedArtworkIDs は承認待ちでなかった（または存在しない）ため変更しなかったアートワーク
+func skippedArtworkIDs(requested, decided []uint) []uint {
+	done := make(map[uint]bool, len(decided))
+	for _, id := range decided {
+		done[id] = true
+	}
+	skipped := make([]uint, 0)
+	for _, id := range requested {
+		if !done[id] {
+			skipped = append(skipped, id)
+			done[id] = true
+		}
+	}
+	return skipped
+}
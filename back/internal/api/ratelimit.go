@@ -0,0 +1,71 @@
+package api
+
+import (
+	"culture-festival-backend/internal/repo"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RateLimitMiddleware struct {
+	limiter *repo.RateLimiter
+}
+
+func NewRateLimitMiddleware(limiter *repo.RateLimiter) *RateLimitMiddleware {
+	return &RateLimitMiddleware{limiter: limiter}
+}
+
+// Limit はクライアントIPごととAPIキーごとのトークンバケットで制限する
+// どちらかが上限に達していれば429とRetry-After（秒）を返す
+// 認証の後に置くこと（APIキーのバケットは認証済みのキーのIDで分ける）
+func (m *RateLimitMiddleware) Limit(name string, perIP, perKey repo.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		buckets := []repo.RateBucket{
+			{Key: fmt.Sprintf("%s:ip:%s", name, c.ClientIP()), Limit: perIP},
+		}
+		if apiKey := CurrentAPIKey(c); apiKey != nil {
+			buckets = append(buckets, repo.RateBucket{Key: fmt.Sprintf("%s:key:%d", name, apiKey.ID), Limit: perKey})
+		}
+
+		allowed, retryAfter, err := m.limiter.Take(buckets...)
+		if err != nil {
+			// Redisの障害でアップロードを止めない
+			fmt.Printf("Rate limit check failed: %s, ip=%s, error=%v\n", name, c.ClientIP(), err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests",
+				"retry_after": seconds,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// OncePerClient はパスパラメータparamの値ごとに、同じクライアントIPからはttlの間1回だけ通す
+// 2回目以降は409を返す
+func (m *RateLimitMiddleware) OncePerClient(name, param string, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		first, err := m.limiter.TakeOnce(fmt.Sprintf("%s:once:%s:%s", name, c.Param(param), c.ClientIP()), ttl)
+		if err != nil {
+			// Redisの障害で止めない（IPごとのレート制限は別に効く）
+			fmt.Printf("Duplicate check failed: %s, ip=%s, error=%v\n", name, c.ClientIP(), err)
+			c.Next()
+			return
+		}
+		if !first {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Already done"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -4,28 +4,52 @@ import (
 	"culture-festival-backend/internal/domain"
 	"culture-festival-backend/internal/repo"
 	"culture-festival-backend/internal/ws"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SceneHandler struct {
-	sceneRepo  *repo.SceneRepository
-	entityRepo *repo.SceneEntityRepository
-	hub        *ws.Hub
+	sceneRepo   *repo.SceneRepository
+	artworkRepo *repo.ArtworkRepository
+	entityRepo  *repo.SceneEntityRepository
+	stateRepo   *repo.EntityStateRepository
+	kindRepo    *repo.AnimationKindRepository
+	assigner    *repo.AnimationAssigner
+	// ハードリセットの退避先
+	snapshotRepo *repo.SceneSnapshotRepository
+	capacityRepo *repo.CapacityRepository
+	hub          *ws.Hub
 }
 
 func NewSceneHandler(
 	sceneRepo *repo.SceneRepository,
+	artworkRepo *repo.ArtworkRepository,
 	entityRepo *repo.SceneEntityRepository,
+	stateRepo *repo.EntityStateRepository,
+	kindRepo *repo.AnimationKindRepository,
+	assigner *repo.AnimationAssigner,
+	snapshotRepo *repo.SceneSnapshotRepository,
+	capacityRepo *repo.CapacityRepository,
 	hub *ws.Hub,
 ) *SceneHandler {
 	return &SceneHandler{
-		sceneRepo:  sceneRepo,
-		entityRepo: entityRepo,
-		hub:        hub,
+		sceneRepo:    sceneRepo,
+		artworkRepo:  artworkRepo,
+		entityRepo:   entityRepo,
+		stateRepo:    stateRepo,
+		kindRepo:     kindRepo,
+		assigner:     assigner,
+		snapshotRepo: snapshotRepo,
+		capacityRepo: capacityRepo,
+		hub:          hub,
 	}
 }
 
@@ -33,6 +57,62 @@ type CreateSceneRequest struct {
 	Name   string `json:"name" binding:"required"`
 	Width  int    `json:"width" binding:"required"`
 	Height int    `json:"height" binding:"required"`
+	// シーン指定のないアップロードをこのシーンにも振り分けるか
+	AcceptUploads bool `json:"accept_uploads"`
+	// 承認されたアートワークだけを表示するか
+	RequireApproval bool `json:"require_approval"`
+}
+
+// UpdateSceneRequest は指定されたフィールドのみ更新する
+type UpdateSceneRequest struct {
+	Name          *string `json:"name"`
+	Width         *int    `json:"width"`
+	Height        *int    `json:"height"`
+	AcceptUploads *bool   `json:"accept_uploads"`
+	// 無効にしても承認待ちのアートワークは承認されるまで追加されない
+	RequireApproval *bool `json:"require_approval"`
+}
+
+type CloneSceneRequest struct {
+	Name string `json:"name"`
+}
+
+// ハードリセットを取り消せる期間
+const resetTrashTTL = 10 * time.Minute
+
+// エンティティのパラメータ上限
+const (
+	maxEntityScale = 4.0
+	maxEntitySpeed = 50.0
+)
+
+// UpdateEntityRequest は指定されたフィールドのみ更新する
+type UpdateEntityRequest struct {
+	InitX         *float64 `json:"init_x"`
+	InitY         *float64 `json:"init_y"`
+	InitVX        *float64 `json:"init_vx"`
+	InitVY        *float64 `json:"init_vy"`
+	InitAngle     *float64 `json:"init_angle"`
+	InitScale     *float64 `json:"init_scale"`
+	AnimationKind *string  `json:"animation_kind"`
+	// 種類だけを変えた場合、以前の上書きはクリアされる
+	AnimationParams *json.RawMessage `json:"animation_params"`
+	Reseed          bool             `json:"reseed"`
+}
+
+type SetUploadScenesRequest struct {
+	SceneIDs []uint `json:"scene_ids"`
+}
+
+type UpdateCapacityRequest struct {
+	// 0なら無制限
+	MaxEntities    int    `json:"max_entities"`
+	EvictionPolicy string `json:"eviction_policy" binding:"required"`
+}
+
+type UpdateAnimationPolicyRequest struct {
+	Policy string                       `json:"policy" binding:"required"`
+	Params domain.AnimationPolicyParams `json:"params"`
 }
 
 type AddEntityRequest struct {
@@ -44,6 +124,8 @@ type AddEntityRequest struct {
 	InitAngle     float64 `json:"init_angle"`
 	InitScale     float64 `json:"init_scale"`
 	AnimationKind string  `json:"animation_kind"`
+	// animation_kinds.default_paramsに対する上書き
+	AnimationParams *json.RawMessage `json:"animation_params"`
 }
 
 func (h *SceneHandler) CreateScene(c *gin.Context) {
@@ -53,16 +135,24 @@ func (h *SceneHandler) CreateScene(c *gin.Context) {
 		return
 	}
 
+	if req.Width <= 0 || req.Height <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Width and height must be positive"})
+		return
+	}
+
 	scene := &domain.Scene{
-		Name:   req.Name,
-		Width:  req.Width,
-		Height: req.Height,
+		Name:            req.Name,
+		Width:           req.Width,
+		Height:          req.Height,
+		AcceptUploads:   req.AcceptUploads,
+		RequireApproval: req.RequireApproval,
 	}
 
 	if err := h.sceneRepo.Create(scene); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create scene"})
 		return
 	}
+	addAuditTarget(c, "scene_id", scene.ID)
 
 	c.JSON(http.StatusOK, scene)
 }
@@ -94,6 +184,152 @@ func (h *SceneHandler) GetSceneByID(c *gin.Context) {
 	c.JSON(http.StatusOK, scene)
 }
 
+func (h *SceneHandler) UpdateScene(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	var req UpdateSceneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	scene, err := h.sceneRepo.GetBasicByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	if req.Name != nil {
+		scene.Name = *req.Name
+	}
+	if req.Width != nil {
+		scene.Width = *req.Width
+	}
+	if req.Height != nil {
+		scene.Height = *req.Height
+	}
+	if req.AcceptUploads != nil {
+		scene.AcceptUploads = *req.AcceptUploads
+	}
+	if req.RequireApproval != nil {
+		scene.RequireApproval = *req.RequireApproval
+	}
+
+	if scene.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if scene.Width <= 0 || scene.Height <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Width and height must be positive"})
+		return
+	}
+
+	// 縮小ではみ出したエンティティは同じ更新で範囲内に収める
+	clamped, err := h.sceneRepo.UpdateAndClamp(scene)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scene"})
+		return
+	}
+
+	// 表示中のディスプレイにシーンサイズの変更を通知
+	h.hub.PublishSceneEvent(scene.ID, ws.Message{
+		Type: "scene.update",
+		Data: map[string]interface{}{
+			"scene_id": scene.ID,
+			"name":     scene.Name,
+			"width":    scene.Width,
+			"height":   scene.Height,
+		},
+	})
+
+	if len(clamped) > 0 {
+		// 報告済みの位置は古いサイズのものなので捨て、収めた初期位置で描き直させる
+		for _, entityID := range clamped {
+			if err := h.stateRepo.Delete(scene.ID, entityID); err != nil {
+				fmt.Printf("Failed to clear entity state: scene_id=%d, entity_id=%d, error=%v\n", scene.ID, entityID, err)
+			}
+		}
+		h.hub.PublishSceneResync(scene, "scene_resized")
+	}
+
+	c.JSON(http.StatusOK, scene)
+}
+
+// DeleteScene はエンティティごとシーンを削除する。ディスプレイノードは削除せず切り離す
+func (h *SceneHandler) DeleteScene(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	if _, err := h.sceneRepo.GetBasicByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	nodes, err := h.sceneRepo.Delete(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete scene"})
+		return
+	}
+
+	// 接続中のディスプレイを待機状態にしてから、シーンのRedis上の状態とイベントログを消す
+	for i := range nodes {
+		h.hub.ApplyDisplayConfig(&nodes[i])
+	}
+	h.hub.ForgetScene(uint(id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Scene deleted successfully",
+		"detached_displays": len(nodes),
+	})
+}
+
+// CloneScene はエンティティとディスプレイ配置をコピーした新しいシーンを作る
+func (h *SceneHandler) CloneScene(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	var req CloneSceneRequest
+	// ボディは省略可
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+	}
+
+	source, err := h.sceneRepo.GetBasicByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = source.Name + " (copy)"
+	}
+
+	clone, err := h.sceneRepo.Clone(source.ID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone scene"})
+		return
+	}
+	addAuditTarget(c, "scene_id", clone.ID)
+
+	c.JSON(http.StatusOK, clone)
+}
+
 func (h *SceneHandler) AddEntity(c *gin.Context) {
 	idStr := c.Param("id")
 	sceneID, err := strconv.ParseUint(idStr, 10, 32)
@@ -108,54 +344,244 @@ func (h *SceneHandler) AddEntity(c *gin.Context) {
 		return
 	}
 
-	// デフォルト値を設定
-	if req.AnimationKind == "" {
-		req.AnimationKind = repo.GetNextAnimationKind()
+	scene, err := h.sceneRepo.GetBasicByID(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	artwork, err := h.artworkRepo.GetByID(req.ArtworkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
+		return
+	}
+	if artwork.Status == domain.ArtworkStatusRejected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Artwork has been rejected"})
+		return
+	}
+	if artwork.TakenDownAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Artwork has been taken down"})
+		return
+	}
+	if artwork.Status == domain.ArtworkStatusPending && scene.RequireApproval {
+		c.JSON(http.StatusConflict, gin.H{"error": "Artwork is pending approval"})
+		return
+	}
+
+	// デフォルト値を設定（animation_kindが空ならPlaceでシーンのポリシーから決める）
+	if req.InitScale == 0 {
+		req.InitScale = 0.25
 	}
 
 	entity := &domain.SceneEntity{
-		SceneID:       uint(sceneID),
-		ArtworkID:     req.ArtworkID,
-		InitX:         req.InitX,
-		InitY:         req.InitY,
-		InitVX:        req.InitVX,
-		InitVY:        req.InitVY,
-		InitAngle:     req.InitAngle,
-		InitScale:     req.InitScale,
-		AnimationKind: req.AnimationKind,
-		RNGSeed:       repo.GetRandomRNGSeed(),
-	}
-
-	if err := h.entityRepo.Create(entity); err != nil {
+		SceneID:         uint(sceneID),
+		ArtworkID:       req.ArtworkID,
+		InitX:           req.InitX,
+		InitY:           req.InitY,
+		InitVX:          req.InitVX,
+		InitVY:          req.InitVY,
+		InitAngle:       req.InitAngle,
+		InitScale:       req.InitScale,
+		AnimationKind:   req.AnimationKind,
+		AnimationParams: req.AnimationParams,
+		RNGSeed:         repo.GetRandomRNGSeed(),
+	}
+
+	if msg := validateEntity(entity, scene); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	// 種類の決定と作成はシーン単位のロック内で行う
+	err = h.assigner.Place(scene, entity, func(entity *domain.SceneEntity) error {
+		if msg := h.validateAnimation(entity); msg != "" {
+			return entityValidationError(msg)
+		}
+		return nil
+	})
+	var invalid entityValidationError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": string(invalid)})
+		return
+	}
+	if errors.Is(err, repo.ErrNoAnimationKind) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No animation kind is enabled"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add entity to scene"})
 		return
 	}
 
-	// WebSocketでブロードキャスト
-	room := fmt.Sprintf("scene:%d", sceneID)
+	// アートワーク情報付きで読み直してブロードキャスト
+	created, err := h.entityRepo.GetByIDWithArtwork(entity.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load created entity"})
+		return
+	}
+
 	message := ws.Message{
 		Type: "entity.add",
-		Data: map[string]interface{}{
-			"entity_id": entity.ID,
-			"artwork_url": fmt.Sprintf("/download/%d", req.ArtworkID), // 簡易版
-			"init": map[string]interface{}{
-				"x": entity.InitX,
-				"y": entity.InitY,
-				"vx": entity.InitVX,
-				"vy": entity.InitVY,
-				"angle": entity.InitAngle,
-				"scale": entity.InitScale,
-			},
-			"animation_kind": entity.AnimationKind,
-			"seed": entity.RNGSeed,
-		},
+		Data: ws.EntityPayload(created),
 	}
 
-	h.hub.BroadcastToRoom(room, message)
+	h.hub.PublishSceneEvent(uint(sceneID), message)
+
+	if created.AnimationKind == "spin_fight" {
+		h.hub.ReconcileFights(uint(sceneID))
+	}
+
+	// 定員を超えたら追加したエンティティ以外を退場させる
+	h.hub.EnforceCapacity(uint(sceneID), entity.ID)
+	addAuditTarget(c, "entity_id", entity.ID)
 
 	c.JSON(http.StatusOK, entity)
 }
 
+// GetUploadScenes はシーン指定のないアップロードの振り分け先を返す
+func (h *SceneHandler) GetUploadScenes(c *gin.Context) {
+	scenes, err := h.sceneRepo.ListUploadTargets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload scenes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scenes)
+}
+
+// SetUploadScenes は振り分け先を置き換える。空配列ならシーン指定のないアップロードは受け付けない
+func (h *SceneHandler) SetUploadScenes(c *gin.Context) {
+	var req SetUploadScenesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, id := range req.SceneIDs {
+		if _, err := h.sceneRepo.GetBasicByID(id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Scene not found: %d", id)})
+			return
+		}
+	}
+
+	if err := h.sceneRepo.SetUploadTargets(req.SceneIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload scenes"})
+		return
+	}
+
+	h.GetUploadScenes(c)
+}
+
+// UpdateAnimationPolicy は新規エンティティへのアニメーション割り当てポリシーを切り替える
+func (h *SceneHandler) UpdateAnimationPolicy(c *gin.Context) {
+	idStr := c.Param("id")
+	sceneID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	var req UpdateAnimationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kinds, err := h.kindRepo.Names()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load animation kinds"})
+		return
+	}
+
+	if err := repo.ValidateAnimationPolicy(req.Policy, &req.Params, kinds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.sceneRepo.GetBasicByID(uint(sceneID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	paramsBytes, _ := json.Marshal(req.Params)
+	params := json.RawMessage(paramsBytes)
+	if err := h.sceneRepo.UpdateAnimationPolicy(uint(sceneID), req.Policy, &params); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update animation policy"})
+		return
+	}
+
+	scene, err := h.sceneRepo.GetBasicByID(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scene"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scene)
+}
+
+// UpdateCapacity はシーンの定員と退場ポリシーを変更し、すぐに適用する
+func (h *SceneHandler) UpdateCapacity(c *gin.Context) {
+	idStr := c.Param("id")
+	sceneID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	var req UpdateCapacityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := repo.ValidateEvictionPolicy(req.MaxEntities, req.EvictionPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.sceneRepo.GetBasicByID(uint(sceneID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	if err := h.capacityRepo.Update(uint(sceneID), req.MaxEntities, req.EvictionPolicy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update capacity"})
+		return
+	}
+
+	// 定員を下げたら超過分を退場させ、上げたらキューから戻す
+	h.hub.EnforceCapacity(uint(sceneID))
+
+	scene, err := h.sceneRepo.GetBasicByID(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load scene"})
+		return
+	}
+
+	c.JSON(http.StatusOK, scene)
+}
+
+// GetQueue はrotateポリシーで画面外に退避中のエンティティを戻る順に返す
+func (h *SceneHandler) GetQueue(c *gin.Context) {
+	idStr := c.Param("id")
+	sceneID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	queued, err := h.capacityRepo.ListQueued(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, queued)
+}
+
+// ResetScene はシーンをリセットする
+//   - ソフトリセット（既定）: データは消さず、全ディスプレイを初期値から物理演算し直させる
+//   - ハードリセット（?hard=true）: エンティティをゴミ箱に退避して削除する。resetTrashTTLの間は取り消せる
 func (h *SceneHandler) ResetScene(c *gin.Context) {
 	idStr := c.Param("id")
 	sceneID, err := strconv.ParseUint(idStr, 10, 32)
@@ -164,23 +590,104 @@ func (h *SceneHandler) ResetScene(c *gin.Context) {
 		return
 	}
 
-	if err := h.sceneRepo.ResetScene(uint(sceneID)); err != nil {
+	hard := false
+	if hardStr := c.Query("hard"); hardStr != "" {
+		hard, err = strconv.ParseBool(hardStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hard flag"})
+			return
+		}
+	}
+
+	scene, err := h.sceneRepo.GetBasicByID(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	if !hard {
+		// 報告済みの状態を捨て、初期値だけのシーン全体を送り直す
+		if err := h.stateRepo.DeleteBySceneID(scene.ID); err != nil {
+			fmt.Printf("Failed to clear entity state: scene_id=%d, error=%v\n", scene.ID, err)
+		}
+		h.hub.PublishSceneResync(scene, ws.ResyncReasonSoftReset)
+		h.hub.ResetClock(ws.SceneRoom(scene.ID))
+
+		c.JSON(http.StatusOK, gin.H{"message": "Scene reset successfully", "hard": false})
+		return
+	}
+
+	if _, err := h.snapshotRepo.DeleteExpired(); err != nil {
+		fmt.Printf("Failed to purge expired trash: %v\n", err)
+	}
+
+	// 取り消し時に位置を戻せるよう揮発状態も一緒に退避する
+	states, err := h.stateRepo.GetBySceneID(scene.ID)
+	if err != nil {
+		fmt.Printf("Failed to load entity state for trash: scene_id=%d, error=%v\n", scene.ID, err)
+	}
+
+	trash, err := h.snapshotRepo.ArchiveAndClear(scene.ID, states, resetTrashTTL)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset scene"})
 		return
 	}
 
+	// 揮発状態もRedisから削除
+	if err := h.stateRepo.DeleteBySceneID(scene.ID); err != nil {
+		fmt.Printf("Failed to clear entity state: scene_id=%d, error=%v\n", scene.ID, err)
+	}
+
 	// WebSocketでブロードキャスト
-	room := fmt.Sprintf("scene:%d", sceneID)
 	message := ws.Message{
 		Type: "scene.reset",
 		Data: map[string]interface{}{
-			"hard": true,
+			"hard":     true,
+			"trash_id": trash.ID,
 		},
 	}
 
-	h.hub.BroadcastToRoom(room, message)
+	h.hub.PublishSceneEvent(scene.ID, message)
+	// 物理演算を揃えて再開できるよう基準時刻もリセット
+	h.hub.ResetClock(ws.SceneRoom(scene.ID))
+	// 進行中の対戦を終了させる
+	h.hub.ReconcileFights(scene.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Scene reset successfully",
+		"hard":       true,
+		"trash_id":   trash.ID,
+		"archived":   trash.EntityCount,
+		"expires_at": trash.ExpiresAt,
+	})
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Scene reset successfully"})
+// GetSceneState はディスプレイから報告された最新のエンティティ状態を返す
+func (h *SceneHandler) GetSceneState(c *gin.Context) {
+	idStr := c.Param("id")
+	sceneID, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return
+	}
+
+	states, err := h.stateRepo.GetBySceneID(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to get entity state"})
+		return
+	}
+
+	entities := make([]domain.EntityState, 0, len(states))
+	for _, state := range states {
+		entities = append(entities, state)
+	}
+	sort.Slice(entities, func(i, j int) bool { return entities[i].EntityID < entities[j].EntityID })
+
+	c.JSON(http.StatusOK, gin.H{
+		"scene_id": sceneID,
+		"ttl_sec":  int(repo.EntityStateTTL.Seconds()),
+		"entities": entities,
+	})
 }
 
 func (h *SceneHandler) UpdateEntity(c *gin.Context) {
@@ -199,16 +706,13 @@ func (h *SceneHandler) UpdateEntity(c *gin.Context) {
 		return
 	}
 
-	var updateData struct {
-		AnimationKind string `json:"animation_kind"`
-	}
-
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var req UpdateEntityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
-	entity, err := h.entityRepo.GetByID(uint(entityID))
+	entity, err := h.entityRepo.GetByIDWithArtwork(uint(entityID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Entity not found"})
 		return
@@ -219,13 +723,135 @@ func (h *SceneHandler) UpdateEntity(c *gin.Context) {
 		return
 	}
 
-	entity.AnimationKind = updateData.AnimationKind
+	scene, err := h.sceneRepo.GetBasicByID(uint(sceneID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return
+	}
+
+	changed := applyEntityUpdate(entity, &req)
+	if len(changed) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	if msg := validateEntity(entity, scene); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if hasChanged(changed, "animation_kind") || hasChanged(changed, "animation_params") {
+		if msg := h.validateAnimation(entity); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
 	if err := h.entityRepo.Update(entity); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update entity"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Entity updated successfully"})
+	// 報告済みの位置・速度が残っていると、後から参加したディスプレイに新しい初期値が反映されない
+	if hasChanged(changed, "init_x") || hasChanged(changed, "init_y") || hasChanged(changed, "init_vx") ||
+		hasChanged(changed, "init_vy") || hasChanged(changed, "init_angle") {
+		if err := h.stateRepo.Delete(entity.SceneID, entity.ID); err != nil {
+			fmt.Printf("Failed to clear entity state: entity_id=%d, error=%v\n", entity.ID, err)
+		}
+	}
+
+	// 表示中のディスプレイに変更をすぐ反映
+	data := ws.EntityPayload(entity)
+	data["changed"] = changed
+	h.hub.PublishSceneEvent(entity.SceneID, ws.Message{
+		Type: "entity.update",
+		Data: data,
+	})
+
+	// spin_fightへの変更・spin_fightからの変更で対戦を組み直す
+	if hasChanged(changed, "animation_kind") {
+		h.hub.ReconcileFights(entity.SceneID)
+	}
+
+	c.JSON(http.StatusOK, entity)
+}
+
+func hasChanged(changed []string, field string) bool {
+	for _, name := range changed {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEntityUpdate は指定されたフィールドのみ反映し、変更したフィールド名を返す
+func applyEntityUpdate(entity *domain.SceneEntity, req *UpdateEntityRequest) []string {
+	var changed []string
+
+	setFloat := func(name string, dst *float64, src *float64) {
+		if src != nil {
+			*dst = *src
+			changed = append(changed, name)
+		}
+	}
+	setFloat("init_x", &entity.InitX, req.InitX)
+	setFloat("init_y", &entity.InitY, req.InitY)
+	setFloat("init_vx", &entity.InitVX, req.InitVX)
+	setFloat("init_vy", &entity.InitVY, req.InitVY)
+	setFloat("init_angle", &entity.InitAngle, req.InitAngle)
+	setFloat("init_scale", &entity.InitScale, req.InitScale)
+
+	if req.AnimationKind != nil {
+		entity.AnimationKind = *req.AnimationKind
+		changed = append(changed, "animation_kind")
+	}
+	if req.AnimationParams != nil {
+		entity.AnimationParams = req.AnimationParams
+		changed = append(changed, "animation_params")
+	} else if req.AnimationKind != nil && entity.AnimationParams != nil {
+		// 別の種類のパラメータは引き継がない
+		entity.AnimationParams = nil
+		changed = append(changed, "animation_params")
+	}
+	if req.Reseed {
+		entity.RNGSeed = repo.GetRandomRNGSeed()
+		changed = append(changed, "rng_seed")
+	}
+
+	return changed
+}
+
+// validateEntity は初期値がシーンの範囲内に収まっているか検証する
+func validateEntity(entity *domain.SceneEntity, scene *domain.Scene) string {
+	if entity.InitX < 0 || entity.InitX > float64(scene.Width) || entity.InitY < 0 || entity.InitY > float64(scene.Height) {
+		return fmt.Sprintf("Position must be within the scene (0-%d, 0-%d)", scene.Width, scene.Height)
+	}
+	if entity.InitScale <= 0 || entity.InitScale > maxEntityScale {
+		return fmt.Sprintf("Scale must be between 0 and %g", maxEntityScale)
+	}
+	if math.Abs(entity.InitVX) > maxEntitySpeed || math.Abs(entity.InitVY) > maxEntitySpeed {
+		return fmt.Sprintf("Velocity must be between -%g and %g", maxEntitySpeed, maxEntitySpeed)
+	}
+	return ""
+}
+
+// validateAnimation はアニメーション種類がレジストリで有効か、上書きパラメータがスキーマに合うかを検証する
+// entityValidationError はAnimationAssigner.Placeの検証で返す、400にするエラー
+type entityValidationError string
+
+func (e entityValidationError) Error() string {
+	return string(e)
+}
+
+func (h *SceneHandler) validateAnimation(entity *domain.SceneEntity) string {
+	kind, err := h.kindRepo.GetByName(entity.AnimationKind)
+	if err != nil || !kind.Enabled {
+		return "Invalid animation kind"
+	}
+	if err := repo.ValidateAnimationParams(kind.ParamSchema, entity.AnimationParams); err != nil {
+		return err.Error()
+	}
+	return ""
 }
 
 func (h *SceneHandler) DeleteEntity(c *gin.Context) {
@@ -260,5 +886,10 @@ func (h *SceneHandler) DeleteEntity(c *gin.Context) {
 		return
 	}
 
+	// 報告済みの状態もPublishEntityRemoveで消す
+	h.hub.PublishEntityRemove(entity, ws.RemoveReasonDeleted)
+	// 空いた枠にrotateのキューから戻す
+	h.hub.EnforceCapacity(entity.SceneID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Entity deleted successfully"})
 }
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"culture-festival-backend/internal/domain"
+	"culture-festival-backend/internal/repo"
+	"culture-festival-backend/internal/ws"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SnapshotHandler struct {
+	sceneRepo    *repo.SceneRepository
+	entityRepo   *repo.SceneEntityRepository
+	stateRepo    *repo.EntityStateRepository
+	snapshotRepo *repo.SceneSnapshotRepository
+	hub          *ws.Hub
+}
+
+func NewSnapshotHandler(
+	sceneRepo *repo.SceneRepository,
+	entityRepo *repo.SceneEntityRepository,
+	stateRepo *repo.EntityStateRepository,
+	snapshotRepo *repo.SceneSnapshotRepository,
+	hub *ws.Hub,
+) *SnapshotHandler {
+	return &SnapshotHandler{
+		sceneRepo:    sceneRepo,
+		entityRepo:   entityRepo,
+		stateRepo:    stateRepo,
+		snapshotRepo: snapshotRepo,
+		hub:          hub,
+	}
+}
+
+type CreateSnapshotRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// Create は現在のエンティティとRedisの揮発状態を名前付きで保存する
+func (h *SnapshotHandler) Create(c *gin.Context) {
+	scene, ok := h.loadScene(c)
+	if !ok {
+		return
+	}
+
+	var req CreateSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exists, err := h.snapshotRepo.ExistsByName(scene.ID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check snapshot name"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "Snapshot name already exists"})
+		return
+	}
+
+	entities, err := h.entityRepo.GetBySceneID(scene.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scene entities"})
+		return
+	}
+
+	states, err := h.stateRepo.GetBySceneID(scene.ID)
+	if err != nil {
+		// 揮発状態がなくても初期値だけで保存する
+		fmt.Printf("Failed to load entity state for snapshot: scene_id=%d, error=%v\n", scene.ID, err)
+	}
+
+	snapshot, err := repo.BuildSnapshot(scene.ID, req.Name, domain.SnapshotKindManual, entities, states)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode snapshot"})
+		return
+	}
+
+	if err := h.snapshotRepo.Create(snapshot); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save snapshot"})
+		return
+	}
+
+	// 一覧と同じ形で返す
+	snapshot.Entities = nil
+	c.JSON(http.StatusOK, snapshot)
+}
+
+func (h *SnapshotHandler) List(c *gin.Context) {
+	scene, ok := h.loadScene(c)
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.snapshotRepo.ListBySceneID(scene.ID, domain.SnapshotKindManual)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get snapshots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// Restore はシーンのエンティティをスナップショットで置き換え、ルーム全体にscene.snapshotを配信する
+func (h *SnapshotHandler) Restore(c *gin.Context) {
+	scene, ok := h.loadScene(c)
+	if !ok {
+		return
+	}
+
+	snapshot, ok := h.loadSnapshot(c, scene.ID, domain.SnapshotKindManual)
+	if !ok {
+		return
+	}
+
+	result, err := h.snapshotRepo.Restore(snapshot, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore snapshot"})
+		return
+	}
+
+	// 旧エンティティの揮発状態を捨ててから書き戻す
+	if err := h.stateRepo.DeleteBySceneID(scene.ID); err != nil {
+		fmt.Printf("Failed to clear entity state: scene_id=%d, error=%v\n", scene.ID, err)
+	}
+	h.applyRestored(scene, result, ws.ResyncReasonSnapshotRestore)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Snapshot restored successfully",
+		"restored": len(result.Entities),
+		"skipped":  result.Skipped,
+	})
+}
+
+// ListTrash はハードリセットで退避され、まだ取り消せるエンティティの一覧を返す
+func (h *SnapshotHandler) ListTrash(c *gin.Context) {
+	scene, ok := h.loadScene(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.snapshotRepo.DeleteExpired(); err != nil {
+		fmt.Printf("Failed to purge expired trash: %v\n", err)
+	}
+
+	trash, err := h.snapshotRepo.ListBySceneID(scene.ID, domain.SnapshotKindTrash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// UndoReset はハードリセットを取り消し、退避したエンティティを現在のシーンに戻す
+// リセット後に追加されたエンティティはそのまま残す
+func (h *SnapshotHandler) UndoReset(c *gin.Context) {
+	scene, ok := h.loadScene(c)
+	if !ok {
+		return
+	}
+
+	trash, ok := h.loadSnapshot(c, scene.ID, domain.SnapshotKindTrash)
+	if !ok {
+		return
+	}
+
+	if trash.ExpiresAt != nil && time.Now().After(*trash.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Undo period has expired"})
+		return
+	}
+
+	result, err := h.snapshotRepo.Restore(trash, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Reset has already been undone"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo reset"})
+		return
+	}
+
+	h.applyRestored(scene, result, ws.ResyncReasonResetUndo)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Reset undone successfully",
+		"restored": len(result.Entities),
+		"skipped":  result.Skipped,
+	})
+}
+
+// applyRestored は復元したエンティティの揮発状態を新しいIDで書き戻し、定員に合わせてからルーム全体を再同期する
+func (h *SnapshotHandler) applyRestored(scene *domain.Scene, result *repo.SnapshotRestoreResult, reason string) {
+	for _, state := range result.States {
+		// 保存時刻のままだとTTL切れで無視されるので今の時刻にする
+		state.UpdatedAt = time.Now()
+		if err := h.stateRepo.Save(state); err != nil {
+			fmt.Printf("Failed to restore entity state: entity_id=%d, error=%v\n", state.EntityID, err)
+		}
+	}
+
+	// キューから戻した分も含めて復元するので、定員を超えた分はポリシーに従って退場させる
+	h.hub.EnforceCapacity(scene.ID)
+
+	h.hub.PublishSceneResync(scene, reason)
+	h.hub.ResetClock(ws.SceneRoom(scene.ID))
+	h.hub.ReconcileFights(scene.ID)
+}
+
+func (h *SnapshotHandler) Delete(c *gin.Context) {
+	scene, ok := h.loadScene(c)
+	if !ok {
+		return
+	}
+
+	snapshot, ok := h.loadSnapshot(c, scene.ID, domain.SnapshotKindManual)
+	if !ok {
+		return
+	}
+
+	if err := h.snapshotRepo.Delete(snapshot.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete snapshot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snapshot deleted successfully"})
+}
+
+func (h *SnapshotHandler) loadScene(c *gin.Context) (*domain.Scene, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scene ID"})
+		return nil, false
+	}
+
+	scene, err := h.sceneRepo.GetBasicByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scene not found"})
+		return nil, false
+	}
+
+	return scene, true
+}
+
+func (h *SnapshotHandler) loadSnapshot(c *gin.Context, sceneID uint, kind string) (*domain.SceneSnapshot, bool) {
+	idStr := c.Param("snapshot_id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+		return nil, false
+	}
+
+	snapshot, err := h.snapshotRepo.GetByID(uint(id))
+	if err != nil || snapshot.SceneID != sceneID || snapshot.Kind != kind {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return nil, false
+	}
+
+	return snapshot, true
+}
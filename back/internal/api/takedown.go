@@ -0,0 +1,156 @@
+package api
+
+import (
+	"culture-festival-backend/internal/ws"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TakedownRequest struct {
+	// 取り下げでは必須
+	Reason string `json:"reason"`
+}
+
+// Takedown は表示中のアートワークを取り下げる
+// 全シーンから外してentity.removeを配信し、ダウンロード・一覧にも出さなくなる。アセットは確認用に残す
+func (h *ArtworkHandler) Takedown(c *gin.Context) {
+	artworkID, reason, ok := h.bindTakedownRequest(c, true)
+	if !ok {
+		return
+	}
+
+	record, entities, err := h.takedownRepo.Takedown(artworkID, reason, moderatorID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Artwork is already taken down"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to take down artwork"})
+		return
+	}
+
+	// エンティティを含んでいたシーンにのみ削除を通知し、空いた枠にrotateのキューから戻す
+	sceneIDs := make(map[uint]bool)
+	for i := range entities {
+		h.hub.PublishEntityRemove(&entities[i], ws.RemoveReasonTakenDown)
+		sceneIDs[entities[i].SceneID] = true
+	}
+	for sceneID := range sceneIDs {
+		h.hub.EnforceCapacity(sceneID)
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// Reinstate は取り下げを解除し、取り下げ時に表示されていたシーンに戻す（位置は新しく決める）
+func (h *ArtworkHandler) Reinstate(c *gin.Context) {
+	artworkID, reason, ok := h.bindTakedownRequest(c, false)
+	if !ok {
+		return
+	}
+
+	record, sceneIDs, err := h.takedownRepo.Reinstate(artworkID, reason, moderatorID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Artwork is not taken down"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reinstate artwork"})
+		return
+	}
+
+	artwork, err := h.artworkRepo.GetByID(artworkID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load artwork"})
+		return
+	}
+
+	entityIDs := make([]uint, 0, len(sceneIDs))
+	for _, sceneID := range sceneIDs {
+		scene, err := h.sceneRepo.GetBasicByID(sceneID)
+		if err != nil {
+			continue
+		}
+		entity, err := h.placeArtwork(scene, artwork)
+		if err != nil {
+			fmt.Printf("Failed to reinstate artwork: artwork_id=%d, scene_id=%d, error=%v\n", artworkID, sceneID, err)
+			continue
+		}
+		entityIDs = append(entityIDs, entity.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"takedown":   record,
+		"entity_ids": entityIDs,
+	})
+}
+
+// ListTakedowns は取り下げ中のアートワークを取り下げ・復帰の記録付きで返す
+func (h *ArtworkHandler) ListTakedowns(c *gin.Context) {
+	artworks, err := h.takedownRepo.ListTakenDown()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get taken down artworks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, artworks)
+}
+
+// GetTakedownHistory はアートワークの取り下げ・復帰の記録を古い順に返す（削除済みのアートワークでも返す）
+func (h *ArtworkHandler) GetTakedownHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid artwork ID"})
+		return
+	}
+
+	records, err := h.takedownRepo.History(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get takedown history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+func (h *ArtworkHandler) bindTakedownRequest(c *gin.Context, requireReason bool) (uint, *string, bool) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid artwork ID"})
+		return 0, nil, false
+	}
+
+	var req TakedownRequest
+	// 復帰は理由を省略できるのでボディなしも受け付ける
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return 0, nil, false
+		}
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if requireReason && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return 0, nil, false
+	}
+	if len([]rune(req.Reason)) > maxModerationReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Reason must be at most %d characters", maxModerationReasonLength)})
+		return 0, nil, false
+	}
+
+	if _, err := h.artworkRepo.GetByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artwork not found"})
+		return 0, nil, false
+	}
+
+	return uint(id), optionalReason(req.Reason), true
+}
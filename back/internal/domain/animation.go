@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AnimationKind はアニメーション種類のレジストリ（animation_kindsテーブル）
+// 新しい演出はマイグレーションなしで行を追加するだけで使える
+type AnimationKind struct {
+	Name        string `json:"name" gorm:"primaryKey;size:32"`
+	Description string `json:"description" gorm:"size:255;not null;default:''"`
+	// パラメータのJSONスキーマ（type: object / properties のサブセット）
+	ParamSchema   *json.RawMessage `json:"param_schema" gorm:"type:jsonb;not null"`
+	DefaultParams *json.RawMessage `json:"default_params" gorm:"type:jsonb;not null"`
+	Enabled       bool             `json:"enabled" gorm:"not null;default:true"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
@@ -16,6 +16,13 @@ type Asset struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// artworks.status
+const (
+	ArtworkStatusPending  = "pending"
+	ArtworkStatusApproved = "approved"
+	ArtworkStatusRejected = "rejected"
+)
+
 type Artwork struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	AssetID   uint      `json:"asset_id" gorm:"not null"`
@@ -24,9 +31,31 @@ type Artwork struct {
 	Tags      *json.RawMessage `json:"tags" gorm:"type:jsonb"`
 	QRToken   string    `json:"qr_token" gorm:"size:48;uniqueIndex;not null"`
 	ThumbPath string    `json:"thumb_path" gorm:"size:255;not null"`
+	LikeCount int       `json:"like_count" gorm:"not null;default:0"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// 審査（却下されたアートワークはダウンロードもできない）
+	Status           string     `json:"status" gorm:"size:16;not null;default:approved"`
+	ModerationReason *string    `json:"moderation_reason" gorm:"size:500"`
+	ModeratedBy      *uint      `json:"moderated_by"`
+	ModeratedAt      *time.Time `json:"moderated_at"`
+
+	// 取り下げ中はシーン・ダウンロード・一覧のどこにも出さない（アセットは残す）
+	TakenDownAt *time.Time `json:"taken_down_at"`
 	
 	// リレーション
 	Asset Asset `json:"asset" gorm:"foreignKey:AssetID"`
 	User  *User `json:"user" gorm:"foreignKey:UserID"`
+	// 承認待ちの間だけ（/api/moderation/pendingで返す）
+	PendingPlacements []PendingPlacement `json:"pending_placements,omitempty" gorm:"foreignKey:ArtworkID"`
+	// 取り下げ中の一覧（/api/takedowns）でだけ読み込む
+	Takedowns []ArtworkTakedown `json:"takedowns,omitempty" gorm:"foreignKey:ArtworkID"`
+}
+
+// PendingPlacement は承認待ちのアートワークを承認後に追加するシーン
+type PendingPlacement struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ArtworkID uint      `json:"artwork_id" gorm:"not null"`
+	SceneID   uint      `json:"scene_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent は状態を変更したリクエストの記録（未認証で拒否されたものも含む）
+type AuditEvent struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	APIKeyID   *uint   `json:"api_key_id" gorm:"column:api_key_id"`
+	APIKeyName *string `json:"api_key_name" gorm:"column:api_key_name;size:100"`
+	Role       *string `json:"role" gorm:"size:16"`
+	Method     string  `json:"method" gorm:"size:8;not null"`
+	// ルート定義（/api/scenes/:id/reset）と実際のパス
+	Route string `json:"route" gorm:"size:255;not null"`
+	Path  string `json:"path" gorm:"size:500;not null"`
+	// 操作対象のID（{"scene_id": [3], "artwork_id": [1, 2]}）
+	Targets    *json.RawMessage `json:"targets" gorm:"type:jsonb;not null"`
+	Summary    string           `json:"summary"`
+	Status     int              `json:"status" gorm:"not null"`
+	DurationMS int64            `json:"duration_ms" gorm:"column:duration_ms;not null"`
+	ClientIP   string           `json:"client_ip" gorm:"size:64"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
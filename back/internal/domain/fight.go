@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// fight_matches.end_reason
+const (
+	FightEndFinished       = "finished"
+	FightEndPartnerRemoved = "partner_removed"
+)
+
+// FightMatch はspin_fightエンティティ同士の対戦（サーバが割り当てる）
+type FightMatch struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	SceneID         uint       `json:"scene_id" gorm:"not null;index"`
+	EntityAID       uint       `json:"entity_a_id" gorm:"column:entity_a_id;not null"`
+	EntityBID       uint       `json:"entity_b_id" gorm:"column:entity_b_id;not null"`
+	ArtworkAID      uint       `json:"artwork_a_id" gorm:"column:artwork_a_id;not null"`
+	ArtworkBID      uint       `json:"artwork_b_id" gorm:"column:artwork_b_id;not null"`
+	WinnerEntityID  *uint      `json:"winner_entity_id"`
+	WinnerArtworkID *uint      `json:"winner_artwork_id"`
+	EndReason       string     `json:"end_reason" gorm:"size:32"`
+	StartedAt       time.Time  `json:"started_at" gorm:"not null"`
+	EndsAt          time.Time  `json:"ends_at" gorm:"not null"`
+	EndedAt         *time.Time `json:"ended_at"`
+}
+
+// FightLeaderboardEntry はアートワークごとの戦績
+type FightLeaderboardEntry struct {
+	ArtworkID uint   `json:"artwork_id"`
+	QRToken   string `json:"qr_token"`
+	Wins      int64  `json:"wins"`
+	Fights    int64  `json:"fights"`
+}
@@ -1,6 +1,27 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// アニメーション割り当てポリシー
+const (
+	AnimationPolicyFixed          = "fixed"
+	AnimationPolicyRoundRobin     = "round_robin"
+	AnimationPolicyWeightedRandom = "weighted_random"
+	AnimationPolicyBalance        = "balance"
+	AnimationPolicyPairAware      = "pair_aware"
+)
+
+// 定員（max_entities）を超えたときに退場させるエンティティの選び方
+const (
+	EvictionPolicyOldest     = "oldest"
+	EvictionPolicyLeastLiked = "least_liked"
+	EvictionPolicyRandom     = "random"
+	// 古い順に画面外のキューへ退避し、空きができたら戻す
+	EvictionPolicyRotate = "rotate"
+)
 
 type Scene struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -9,11 +30,32 @@ type Scene struct {
 	Height    int       `json:"height" gorm:"not null"`
 	CreatedAt time.Time `json:"created_at"`
 
+	// シーン指定のないアップロードの振り分け先か（opsが設定）
+	AcceptUploads bool `json:"accept_uploads" gorm:"not null;default:false"`
+	// 承認されたアートワークだけを表示するか
+	RequireApproval bool `json:"require_approval" gorm:"not null;default:false"`
+
+	// 新規エンティティへのアニメーション割り当て
+	AnimationPolicy       string           `json:"animation_policy" gorm:"size:32;not null;default:fixed"`
+	AnimationPolicyParams *json.RawMessage `json:"animation_policy_params" gorm:"type:jsonb"`
+	AnimationRRIndex      int64            `json:"-" gorm:"column:animation_rr_index;not null;default:0"`
+
+	// 表示するエンティティの上限（0なら無制限）と超過時の退場ポリシー
+	MaxEntities    int    `json:"max_entities" gorm:"not null;default:0"`
+	EvictionPolicy string `json:"eviction_policy" gorm:"size:16;not null;default:oldest"`
+
 	// リレーション
 	Entities     []SceneEntity `json:"entities" gorm:"foreignKey:SceneID"`
 	DisplayNodes []DisplayNode `json:"display_nodes" gorm:"foreignKey:SceneID"`
 }
 
+// AnimationPolicyParams はポリシーごとのパラメータ（scenes.animation_policy_params）
+type AnimationPolicyParams struct {
+	Kind    string             `json:"kind,omitempty"`    // fixed
+	Kinds   []string           `json:"kinds,omitempty"`   // round_robin / balance / pair_aware の候補
+	Weights map[string]float64 `json:"weights,omitempty"` // weighted_random
+}
+
 type SceneEntity struct {
 	ID            uint      `json:"id" gorm:"primaryKey"`
 	SceneID       uint      `json:"scene_id" gorm:"not null;index"`
@@ -24,18 +66,39 @@ type SceneEntity struct {
 	InitVY        float64   `json:"init_vy" gorm:"not null"`
 	InitAngle     float64   `json:"init_angle" gorm:"not null;default:0"`
 	InitScale     float64   `json:"init_scale" gorm:"not null;default:0.25"`
-	AnimationKind string    `json:"animation_kind" gorm:"type:enum('pulsate','disperse','explode','spin_fight','stream_in');not null"`
+	AnimationKind string    `json:"animation_kind" gorm:"size:32;not null"`
 	RNGSeed       int64     `json:"rng_seed" gorm:"not null"`
 	CreatedAt     time.Time `json:"created_at"`
 
+	// animation_kinds.default_paramsに対する上書き
+	AnimationParams *json.RawMessage `json:"animation_params" gorm:"type:jsonb"`
+
 	// リレーション
 	Scene   Scene   `json:"scene" gorm:"foreignKey:SceneID"`
 	Artwork Artwork `json:"artwork" gorm:"foreignKey:ArtworkID"`
 }
 
+// QueuedEntity はrotateポリシーで画面外に退避したエンティティ
+type QueuedEntity struct {
+	ID              uint             `json:"id" gorm:"primaryKey"`
+	SceneID         uint             `json:"scene_id" gorm:"not null;index"`
+	ArtworkID       uint             `json:"artwork_id" gorm:"not null"`
+	InitX           float64          `json:"init_x" gorm:"not null"`
+	InitY           float64          `json:"init_y" gorm:"not null"`
+	InitVX          float64          `json:"init_vx" gorm:"not null"`
+	InitVY          float64          `json:"init_vy" gorm:"not null"`
+	InitAngle       float64          `json:"init_angle" gorm:"not null;default:0"`
+	InitScale       float64          `json:"init_scale" gorm:"not null;default:0.25"`
+	AnimationKind   string           `json:"animation_kind" gorm:"size:32;not null"`
+	AnimationParams *json.RawMessage `json:"animation_params" gorm:"type:jsonb"`
+	RNGSeed         int64            `json:"rng_seed" gorm:"not null"`
+	QueuedAt        time.Time        `json:"queued_at" gorm:"not null"`
+}
+
 type DisplayNode struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	SceneID     uint      `json:"scene_id" gorm:"not null;index"`
+	ID uint `json:"id" gorm:"primaryKey"`
+	// シーン削除で切り離されたノードはnil（再割り当てまで何も表示しない）
+	SceneID     *uint     `json:"scene_id" gorm:"index"`
 	Name        string    `json:"name" gorm:"size:100;not null"`
 	ViewportX   int       `json:"viewport_x" gorm:"not null"`
 	ViewportY   int       `json:"viewport_y" gorm:"not null"`
@@ -48,5 +111,5 @@ type DisplayNode struct {
 	CreatedAt   time.Time `json:"created_at"`
 
 	// リレーション
-	Scene Scene `json:"scene" gorm:"foreignKey:SceneID"`
+	Scene *Scene `json:"scene" gorm:"foreignKey:SceneID"`
 }
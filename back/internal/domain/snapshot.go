@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// scene_snapshots.kind
+const (
+	SnapshotKindManual = "manual"
+	// ハードリセットで退避したエンティティ（expires_atまで取り消せる）
+	SnapshotKindTrash = "trash"
+)
+
+// SceneSnapshot はシーンのエンティティ配置とRedisの揮発状態を名前付きで保存したもの
+type SceneSnapshot struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	SceneID     uint       `json:"scene_id" gorm:"not null;index"`
+	Name        string     `json:"name" gorm:"size:100;not null"`
+	Kind        string     `json:"kind" gorm:"size:16;not null;default:manual"`
+	EntityCount int        `json:"entity_count" gorm:"not null"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// []SnapshotEntityのJSON（一覧では読み込まない）
+	Entities  *json.RawMessage `json:"entities,omitempty" gorm:"type:jsonb;not null"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// SnapshotEntity はスナップショット内のエンティティ（IDは復元時に振り直す）
+type SnapshotEntity struct {
+	ArtworkID       uint             `json:"artwork_id"`
+	InitX           float64          `json:"init_x"`
+	InitY           float64          `json:"init_y"`
+	InitVX          float64          `json:"init_vx"`
+	InitVY          float64          `json:"init_vy"`
+	InitAngle       float64          `json:"init_angle"`
+	InitScale       float64          `json:"init_scale"`
+	AnimationKind   string           `json:"animation_kind"`
+	AnimationParams *json.RawMessage `json:"animation_params"`
+	RNGSeed         int64            `json:"rng_seed"`
+	State           *EntityState     `json:"state,omitempty"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// EntityState はディスプレイから報告されたエンティティの揮発状態（Redisに保存）
+type EntityState struct {
+	EntityID   uint      `json:"entity_id"`
+	SceneID    uint      `json:"scene_id"`
+	X          float64   `json:"x"`
+	Y          float64   `json:"y"`
+	VX         float64   `json:"vx"`
+	VY         float64   `json:"vy"`
+	Angle      float64   `json:"angle"`
+	Scale      float64   `json:"scale"`
+	TS         int64     `json:"ts"`
+	ReportedBy string    `json:"reported_by"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
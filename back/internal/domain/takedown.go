@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// artwork_takedowns.action
+const (
+	TakedownActionTakedown  = "takedown"
+	TakedownActionReinstate = "reinstate"
+)
+
+// ArtworkTakedown はアートワークの取り下げ・復帰の記録（誰が・いつ・なぜ）
+type ArtworkTakedown struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	ArtworkID   uint    `json:"artwork_id" gorm:"not null;index"`
+	Action      string  `json:"action" gorm:"size:16;not null"`
+	Reason      *string `json:"reason" gorm:"size:500"`
+	PerformedBy *uint   `json:"performed_by"`
+	// 取り下げ時に表示されていた（復帰時に戻した）シーンのID配列
+	SceneIDs  *json.RawMessage `json:"scene_ids" gorm:"type:jsonb;not null"`
+	CreatedAt time.Time        `json:"created_at"`
+}
@@ -2,6 +2,13 @@ package domain
 
 import "time"
 
+// APIキーのロール
+const (
+	RoleUpload  = "upload"
+	RoleDisplay = "display"
+	RoleOps     = "ops"
+)
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Name      string    `json:"name" gorm:"size:100;not null"`
@@ -9,9 +16,10 @@ type User struct {
 }
 
 type APIKey struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Name      string    `json:"name" gorm:"size:100;not null"`
-	Token     string    `json:"token" gorm:"size:40;uniqueIndex;not null"`
-	Role      string    `json:"role" gorm:"type:enum('upload','display','ops');not null"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Name      string     `json:"name" gorm:"size:100;not null"`
+	Token     string     `json:"token" gorm:"size:40;uniqueIndex;not null"`
+	Role      string     `json:"role" gorm:"type:enum('upload','display','ops');not null"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
 }
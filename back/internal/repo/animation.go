@@ -0,0 +1,275 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"gorm.io/gorm"
+)
+
+const (
+	pairedAnimationKind = "spin_fight"
+	// 種類の決定とエンティティの作成をシーン単位で直列化するadvisory lockの名前空間
+	animationLockNamespace = 13
+)
+
+// ErrNoAnimationKind はレジストリに有効なアニメーション種類がひとつもないときに返す
+var ErrNoAnimationKind = errors.New("no animation kind is enabled")
+
+// AnimationAssigner はシーンのポリシーに従って新規エンティティのアニメーション種類を決める
+type AnimationAssigner struct {
+	db    *gorm.DB
+	kinds *AnimationKindRepository
+}
+
+func NewAnimationAssigner(db *gorm.DB, kinds *AnimationKindRepository) *AnimationAssigner {
+	return &AnimationAssigner{db: db, kinds: kinds}
+}
+
+// Place はentity.AnimationKindが空ならシーンのポリシーで種類を決め、エンティティを作成する
+// balance/pair_awareが同時アップロードで同じ判定をしないよう、数える所から作成までを
+// シーン単位のadvisory lockを取った1つのトランザクションで行う（複数インスタンスでも直列化される）
+// validateがエラーを返せば作成せずにそのエラーを返す
+func (a *AnimationAssigner) Place(scene *domain.Scene, entity *domain.SceneEntity, validate func(*domain.SceneEntity) error) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", animationLockNamespace, scene.ID).Error; err != nil {
+			return err
+		}
+
+		if entity.AnimationKind == "" {
+			kind, err := a.next(tx, scene)
+			if err != nil {
+				return err
+			}
+			entity.AnimationKind = kind
+		}
+		if validate != nil {
+			if err := validate(entity); err != nil {
+				return err
+			}
+		}
+		return tx.Create(entity).Error
+	})
+}
+
+// next はシーンに追加するエンティティのアニメーション種類を返す（Placeのロック内で呼ぶ）
+func (a *AnimationAssigner) next(tx *gorm.DB, scene *domain.Scene) (string, error) {
+	params, err := ParseAnimationPolicyParams(scene.AnimationPolicyParams)
+	if err != nil {
+		return "", err
+	}
+
+	// レジストリで無効化された種類は候補から外す
+	enabled, err := a.kinds.Names()
+	if err != nil {
+		return "", err
+	}
+	if len(enabled) == 0 {
+		return "", ErrNoAnimationKind
+	}
+	// 候補がないときは有効な種類のうち最初に登録されたものにする
+	fallback := enabled[0]
+
+	if scene.AnimationPolicy == domain.AnimationPolicyFixed {
+		if containsString(enabled, params.Kind) {
+			return params.Kind, nil
+		}
+		return fallback, nil
+	}
+
+	kinds := candidateKinds(params, enabled)
+	if len(kinds) == 0 {
+		return fallback, nil
+	}
+
+	switch scene.AnimationPolicy {
+	case domain.AnimationPolicyRoundRobin:
+		return nextRoundRobin(tx, scene.ID, kinds)
+	case domain.AnimationPolicyWeightedRandom:
+		return nextWeighted(params.Weights, kinds), nil
+	case domain.AnimationPolicyBalance:
+		return nextBalanced(tx, scene.ID, kinds)
+	case domain.AnimationPolicyPairAware:
+		return nextPairAware(tx, scene.ID, kinds)
+	default:
+		return fallback, nil
+	}
+}
+
+// nextRoundRobin はDBのカウンタを原子的に進めるので複数リクエスト・複数インスタンスでも安全
+func nextRoundRobin(tx *gorm.DB, sceneID uint, kinds []string) (string, error) {
+	var index int64
+	err := tx.Raw(
+		"UPDATE scenes SET animation_rr_index = animation_rr_index + 1 WHERE id = ? RETURNING animation_rr_index",
+		sceneID,
+	).Scan(&index).Error
+	if err != nil {
+		return "", err
+	}
+	return kinds[(index-1)%int64(len(kinds))], nil
+}
+
+// nextWeighted は重みに比例して選ぶ（kindsは空でないこと。重みがすべて0なら先頭）
+func nextWeighted(weights map[string]float64, kinds []string) string {
+	total := 0.0
+	for _, kind := range kinds {
+		total += weights[kind]
+	}
+	if total <= 0 {
+		return kinds[0]
+	}
+
+	// mapの順序は不定なのでkindsの順で累積する
+	r := rand.Float64() * total
+	for _, kind := range kinds {
+		w := weights[kind]
+		if w <= 0 {
+			continue
+		}
+		if r < w {
+			return kind
+		}
+		r -= w
+	}
+	// 浮動小数点の誤差で抜けた場合は重みのある最後の種類
+	for i := len(kinds) - 1; i >= 0; i-- {
+		if weights[kinds[i]] > 0 {
+			return kinds[i]
+		}
+	}
+	return kinds[0]
+}
+
+// nextBalanced はシーン内で最も数の少ない種類を選ぶ
+func nextBalanced(tx *gorm.DB, sceneID uint, kinds []string) (string, error) {
+	counts, err := countByKind(tx, sceneID)
+	if err != nil {
+		return "", err
+	}
+
+	best := kinds[0]
+	for _, kind := range kinds[1:] {
+		if counts[kind] < counts[best] {
+			best = kind
+		}
+	}
+	return best, nil
+}
+
+// nextPairAware はspin_fightが奇数ならペア相手としてspin_fightを、それ以外はbalanceで選ぶ
+func nextPairAware(tx *gorm.DB, sceneID uint, kinds []string) (string, error) {
+	counts, err := countByKind(tx, sceneID)
+	if err != nil {
+		return "", err
+	}
+
+	if counts[pairedAnimationKind]%2 == 1 && containsString(kinds, pairedAnimationKind) {
+		return pairedAnimationKind, nil
+	}
+
+	best := kinds[0]
+	for _, kind := range kinds[1:] {
+		if counts[kind] < counts[best] {
+			best = kind
+		}
+	}
+	return best, nil
+}
+
+func countByKind(tx *gorm.DB, sceneID uint) (map[string]int64, error) {
+	var rows []struct {
+		AnimationKind string
+		Count         int64
+	}
+	err := tx.Model(&domain.SceneEntity{}).
+		Select("animation_kind, COUNT(*) AS count").
+		Where("scene_id = ?", sceneID).
+		Group("animation_kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.AnimationKind] = row.Count
+	}
+	return counts, nil
+}
+
+// candidateKinds はポリシーで指定された候補のうち有効なもの（指定がなければ有効な全種類）を返す
+func candidateKinds(params *domain.AnimationPolicyParams, enabled []string) []string {
+	if len(params.Kinds) == 0 {
+		return enabled
+	}
+	var kinds []string
+	for _, kind := range params.Kinds {
+		if containsString(enabled, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+func ParseAnimationPolicyParams(raw *json.RawMessage) (*domain.AnimationPolicyParams, error) {
+	params := &domain.AnimationPolicyParams{}
+	if raw == nil || len(*raw) == 0 {
+		return params, nil
+	}
+	if err := json.Unmarshal(*raw, params); err != nil {
+		return nil, fmt.Errorf("invalid animation policy params: %v", err)
+	}
+	return params, nil
+}
+
+// ValidateAnimationPolicy はポリシーとパラメータの組み合わせを検証する
+// validKindsにはレジストリで有効な種類を渡す
+func ValidateAnimationPolicy(policy string, params *domain.AnimationPolicyParams, validKinds []string) error {
+	for _, kind := range params.Kinds {
+		if !containsString(validKinds, kind) {
+			return fmt.Errorf("invalid animation kind: %s", kind)
+		}
+	}
+
+	switch policy {
+	case domain.AnimationPolicyFixed:
+		if !containsString(validKinds, params.Kind) {
+			return errors.New("fixed policy requires a valid kind")
+		}
+	case domain.AnimationPolicyRoundRobin, domain.AnimationPolicyBalance:
+		return nil
+	case domain.AnimationPolicyPairAware:
+		if len(params.Kinds) > 0 && !containsString(params.Kinds, pairedAnimationKind) {
+			return fmt.Errorf("pair_aware policy requires %s in kinds", pairedAnimationKind)
+		}
+	case domain.AnimationPolicyWeightedRandom:
+		total := 0.0
+		for kind, w := range params.Weights {
+			if !containsString(validKinds, kind) {
+				return fmt.Errorf("invalid animation kind: %s", kind)
+			}
+			if w < 0 {
+				return errors.New("weights must not be negative")
+			}
+			total += w
+		}
+		if total <= 0 {
+			return errors.New("weighted_random policy requires at least one positive weight")
+		}
+	default:
+		return fmt.Errorf("unknown animation policy: %s", policy)
+	}
+	return nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
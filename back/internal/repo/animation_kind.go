@@ -0,0 +1,161 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+type AnimationKindRepository struct {
+	db *gorm.DB
+}
+
+func NewAnimationKindRepository(db *gorm.DB) *AnimationKindRepository {
+	return &AnimationKindRepository{db: db}
+}
+
+func (r *AnimationKindRepository) Create(kind *domain.AnimationKind) error {
+	return r.db.Create(kind).Error
+}
+
+func (r *AnimationKindRepository) GetByName(name string) (*domain.AnimationKind, error) {
+	var kind domain.AnimationKind
+	err := r.db.Where("name = ?", name).First(&kind).Error
+	if err != nil {
+		return nil, err
+	}
+	return &kind, nil
+}
+
+func (r *AnimationKindRepository) List(includeDisabled bool) ([]domain.AnimationKind, error) {
+	var kinds []domain.AnimationKind
+	query := r.db.Order("created_at, name")
+	if !includeDisabled {
+		query = query.Where("enabled")
+	}
+	err := query.Find(&kinds).Error
+	return kinds, err
+}
+
+// Names は有効なアニメーション種類の名前を登録順に返す
+func (r *AnimationKindRepository) Names() ([]string, error) {
+	var names []string
+	err := r.db.Model(&domain.AnimationKind{}).Where("enabled").Order("created_at, name").Pluck("name", &names).Error
+	return names, err
+}
+
+func (r *AnimationKindRepository) Update(kind *domain.AnimationKind) error {
+	return r.db.Save(kind).Error
+}
+
+// animationParamSchema はJSONスキーマのうちパラメータ検証に使うサブセット
+type animationParamSchema struct {
+	Type       string                            `json:"type"`
+	Properties map[string]animationParamProperty `json:"properties"`
+}
+
+type animationParamProperty struct {
+	Type    string        `json:"type"`
+	Minimum *float64      `json:"minimum"`
+	Maximum *float64      `json:"maximum"`
+	Enum    []interface{} `json:"enum"`
+}
+
+func parseAnimationParamSchema(raw *json.RawMessage) (*animationParamSchema, error) {
+	schema := &animationParamSchema{}
+	if raw == nil || len(*raw) == 0 {
+		return schema, nil
+	}
+	if err := json.Unmarshal(*raw, schema); err != nil {
+		return nil, fmt.Errorf("invalid param schema: %v", err)
+	}
+	if schema.Type != "" && schema.Type != "object" {
+		return nil, errors.New("param schema type must be object")
+	}
+	for name, prop := range schema.Properties {
+		switch prop.Type {
+		case "number", "integer", "boolean", "string":
+		default:
+			return nil, fmt.Errorf("unsupported type for param %s: %q", name, prop.Type)
+		}
+	}
+	return schema, nil
+}
+
+// ValidateAnimationParamSchema はレジストリに登録するスキーマとデフォルト値を検証する
+func ValidateAnimationParamSchema(schema, defaults *json.RawMessage) error {
+	if _, err := parseAnimationParamSchema(schema); err != nil {
+		return err
+	}
+	return ValidateAnimationParams(schema, defaults)
+}
+
+// ValidateAnimationParams はパラメータがスキーマに合っているか検証する
+// スキーマにないキーはタイプミスとみなして拒否する
+func ValidateAnimationParams(schema, params *json.RawMessage) error {
+	if params == nil || len(*params) == 0 || string(*params) == "null" {
+		return nil
+	}
+
+	s, err := parseAnimationParamSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(*params, &values); err != nil {
+		return errors.New("animation params must be a JSON object")
+	}
+
+	for name, value := range values {
+		prop, ok := s.Properties[name]
+		if !ok {
+			return fmt.Errorf("unknown animation param: %s", name)
+		}
+		if err := prop.validate(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p animationParamProperty) validate(name string, value interface{}) error {
+	switch p.Type {
+	case "number", "integer":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("param %s must be a %s", name, p.Type)
+		}
+		if p.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("param %s must be an integer", name)
+		}
+		if p.Minimum != nil && n < *p.Minimum {
+			return fmt.Errorf("param %s must be >= %g", name, *p.Minimum)
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			return fmt.Errorf("param %s must be <= %g", name, *p.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("param %s must be a boolean", name)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("param %s must be a string", name)
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		for _, allowed := range p.Enum {
+			if allowed == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("param %s must be one of %v", name, p.Enum)
+	}
+	return nil
+}
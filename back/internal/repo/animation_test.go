@@ -0,0 +1,238 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCandidateKinds(t *testing.T) {
+	enabled := []string{"disperse", "pulsate", "spin_fight"}
+
+	tests := []struct {
+		name  string
+		kinds []string
+		want  []string
+	}{
+		{name: "指定なしは有効な全種類", kinds: nil, want: enabled},
+		{name: "指定した順に残す", kinds: []string{"spin_fight", "pulsate"}, want: []string{"spin_fight", "pulsate"}},
+		{name: "無効な種類は外す", kinds: []string{"explode", "pulsate"}, want: []string{"pulsate"}},
+		{name: "すべて無効なら空", kinds: []string{"explode"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := candidateKinds(&domain.AnimationPolicyParams{Kinds: tt.kinds}, enabled)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidateKinds(%v) = %v, want %v", tt.kinds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWeighted(t *testing.T) {
+	kinds := []string{"disperse", "pulsate", "spin_fight"}
+
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		want    string
+	}{
+		{name: "重みのある種類だけが選ばれる", weights: map[string]float64{"pulsate": 1}, want: "pulsate"},
+		{name: "候補外の重みは無視する", weights: map[string]float64{"explode": 10, "spin_fight": 1}, want: "spin_fight"},
+		{name: "重みがすべて0なら先頭", weights: map[string]float64{"pulsate": 0}, want: "disperse"},
+		{name: "重みの指定なしなら先頭", weights: nil, want: "disperse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 乱数を使うので何度引いても同じ結果になることを確かめる
+			for i := 0; i < 100; i++ {
+				if got := nextWeighted(tt.weights, kinds); got != tt.want {
+					t.Fatalf("nextWeighted(%v) = %s, want %s", tt.weights, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateAnimationPolicy(t *testing.T) {
+	valid := []string{"disperse", "pulsate", "spin_fight"}
+
+	tests := []struct {
+		name    string
+		policy  string
+		params  domain.AnimationPolicyParams
+		wantErr bool
+	}{
+		{name: "fixed", policy: domain.AnimationPolicyFixed, params: domain.AnimationPolicyParams{Kind: "pulsate"}},
+		{name: "fixedで無効な種類", policy: domain.AnimationPolicyFixed, params: domain.AnimationPolicyParams{Kind: "explode"}, wantErr: true},
+		{name: "round_robin", policy: domain.AnimationPolicyRoundRobin, params: domain.AnimationPolicyParams{Kinds: []string{"pulsate", "disperse"}}},
+		{name: "候補に無効な種類", policy: domain.AnimationPolicyBalance, params: domain.AnimationPolicyParams{Kinds: []string{"explode"}}, wantErr: true},
+		{name: "pair_awareはspin_fightが必要", policy: domain.AnimationPolicyPairAware, params: domain.AnimationPolicyParams{Kinds: []string{"pulsate"}}, wantErr: true},
+		{name: "pair_aware", policy: domain.AnimationPolicyPairAware, params: domain.AnimationPolicyParams{Kinds: []string{"spin_fight", "pulsate"}}},
+		{name: "weighted_random", policy: domain.AnimationPolicyWeightedRandom, params: domain.AnimationPolicyParams{Weights: map[string]float64{"pulsate": 2, "disperse": 0}}},
+		{name: "負の重み", policy: domain.AnimationPolicyWeightedRandom, params: domain.AnimationPolicyParams{Weights: map[string]float64{"pulsate": -1, "disperse": 2}}, wantErr: true},
+		{name: "重みがすべて0", policy: domain.AnimationPolicyWeightedRandom, params: domain.AnimationPolicyParams{Weights: map[string]float64{"pulsate": 0}}, wantErr: true},
+		{name: "未知のポリシー", policy: "shuffle", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAnimationPolicy(tt.policy, &tt.params, valid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAnimationPolicy(%s) error = %v, wantErr %v", tt.policy, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// setEnabledKinds はレジストリでkindsだけを有効にする（テストのトランザクション内なので元に戻る）
+func setEnabledKinds(t *testing.T, db *gorm.DB, kinds []string) {
+	t.Helper()
+	if err := db.Exec("UPDATE animation_kinds SET enabled = COALESCE(name IN ?, false)", kinds).Error; err != nil {
+		t.Fatalf("failed to update animation kinds: %v", err)
+	}
+}
+
+func TestAnimationAssignerPlace(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		params   domain.AnimationPolicyParams
+		existing []string
+		// nilならレジストリを変更しない
+		enabled []string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:   "fixed",
+			policy: domain.AnimationPolicyFixed,
+			params: domain.AnimationPolicyParams{Kind: "explode"},
+			want:   []string{"explode", "explode"},
+		},
+		{
+			name:    "fixedの種類が無効なら有効な最初の種類",
+			policy:  domain.AnimationPolicyFixed,
+			params:  domain.AnimationPolicyParams{Kind: "explode"},
+			enabled: []string{"pulsate"},
+			want:    []string{"pulsate"},
+		},
+		{
+			name:   "round_robin",
+			policy: domain.AnimationPolicyRoundRobin,
+			params: domain.AnimationPolicyParams{Kinds: []string{"pulsate", "explode"}},
+			want:   []string{"pulsate", "explode", "pulsate"},
+		},
+		{
+			name:   "weighted_random",
+			policy: domain.AnimationPolicyWeightedRandom,
+			params: domain.AnimationPolicyParams{Weights: map[string]float64{"disperse": 1}},
+			want:   []string{"disperse", "disperse"},
+		},
+		{
+			name:     "balanceは最も少ない種類",
+			policy:   domain.AnimationPolicyBalance,
+			params:   domain.AnimationPolicyParams{Kinds: []string{"pulsate", "explode"}},
+			existing: []string{"pulsate", "pulsate", "explode"},
+			want:     []string{"explode", "pulsate", "explode"},
+		},
+		{
+			name:     "pair_awareはspin_fightが奇数ならspin_fight",
+			policy:   domain.AnimationPolicyPairAware,
+			params:   domain.AnimationPolicyParams{Kinds: []string{"spin_fight", "pulsate"}},
+			existing: []string{"spin_fight"},
+			want:     []string{"spin_fight", "pulsate", "pulsate"},
+		},
+		{
+			name:    "候補がすべて無効なら有効な最初の種類",
+			policy:  domain.AnimationPolicyBalance,
+			params:  domain.AnimationPolicyParams{Kinds: []string{"explode"}},
+			enabled: []string{"stream_in"},
+			want:    []string{"stream_in"},
+		},
+		{
+			name:    "有効な種類がなければエラー",
+			policy:  domain.AnimationPolicyRoundRobin,
+			enabled: []string{},
+			want:    []string{""},
+			wantErr: ErrNoAnimationKind,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			if tt.enabled != nil {
+				setEnabledKinds(t, db, tt.enabled)
+			}
+
+			params, err := json.Marshal(tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw := json.RawMessage(params)
+			scene := createTestScene(t, db, domain.Scene{AnimationPolicy: tt.policy, AnimationPolicyParams: &raw})
+			artwork := createTestArtwork(t, db, 0)
+			for _, kind := range tt.existing {
+				createTestEntity(t, db, scene.ID, artwork.ID, kind)
+			}
+
+			assigner := NewAnimationAssigner(db, NewAnimationKindRepository(db))
+			var got []string
+			for range tt.want {
+				entity := &domain.SceneEntity{SceneID: scene.ID, ArtworkID: artwork.ID, InitScale: 0.25, RNGSeed: 1}
+				err := assigner.Place(scene, entity, nil)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Place() error = %v, want %v", err, tt.wantErr)
+				}
+				got = append(got, entity.AnimationKind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assigned kinds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnimationAssignerPlaceKeepsRequestedKind(t *testing.T) {
+	db := testDB(t)
+	scene := createTestScene(t, db, domain.Scene{AnimationPolicy: domain.AnimationPolicyRoundRobin})
+	artwork := createTestArtwork(t, db, 0)
+	assigner := NewAnimationAssigner(db, NewAnimationKindRepository(db))
+
+	entity := &domain.SceneEntity{SceneID: scene.ID, ArtworkID: artwork.ID, AnimationKind: "stream_in", InitScale: 0.25, RNGSeed: 1}
+	if err := assigner.Place(scene, entity, nil); err != nil {
+		t.Fatalf("Place() error = %v", err)
+	}
+	if entity.AnimationKind != "stream_in" || entity.ID == 0 {
+		t.Errorf("entity = %+v, want created with stream_in", entity)
+	}
+}
+
+func TestAnimationAssignerPlaceValidationError(t *testing.T) {
+	db := testDB(t)
+	scene := createTestScene(t, db, domain.Scene{AnimationPolicy: domain.AnimationPolicyRoundRobin})
+	artwork := createTestArtwork(t, db, 0)
+	assigner := NewAnimationAssigner(db, NewAnimationKindRepository(db))
+
+	invalid := errors.New("invalid params")
+	entity := &domain.SceneEntity{SceneID: scene.ID, ArtworkID: artwork.ID, InitScale: 0.25, RNGSeed: 1}
+	err := assigner.Place(scene, entity, func(*domain.SceneEntity) error { return invalid })
+	if !errors.Is(err, invalid) {
+		t.Fatalf("Place() error = %v, want %v", err, invalid)
+	}
+
+	var count int64
+	if err := db.Model(&domain.SceneEntity{}).Where("scene_id = ?", scene.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("entities = %d, want 0 after validation error", count)
+	}
+}
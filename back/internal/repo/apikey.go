@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"crypto/rand"
+	"culture-festival-backend/internal/domain"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(apiKey *domain.APIKey) error {
+	return r.db.Create(apiKey).Error
+}
+
+// GetByToken は失効していないAPIキーのみ返す
+func (r *APIKeyRepository) GetByToken(token string) (*domain.APIKey, error) {
+	var apiKey domain.APIKey
+	// tokenはCHAR(40)なので末尾の空白をトリムして比較
+	trimmedToken := strings.TrimSpace(token)
+	err := r.db.Where("TRIM(token) = ? AND revoked_at IS NULL", trimmedToken).First(&apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *APIKeyRepository) GetByID(id uint) (*domain.APIKey, error) {
+	var apiKey domain.APIKey
+	err := r.db.First(&apiKey, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// List はロールで絞り込んだAPIキー一覧を返す（roleが空なら全件）
+func (r *APIKeyRepository) List(role string, includeRevoked bool) ([]domain.APIKey, error) {
+	var apiKeys []domain.APIKey
+	query := r.db.Order("id ASC")
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+	err := query.Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+func (r *APIKeyRepository) CountActiveByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.APIKey{}).
+		Where("role = ? AND revoked_at IS NULL", role).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *APIKeyRepository) Revoke(id uint) error {
+	return r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Rotate は既存キーを失効させ、同じ名前・ロールで新しいトークンのキーを発行する
+func (r *APIKeyRepository) Rotate(id uint) (*domain.APIKey, error) {
+	var newKey *domain.APIKey
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var oldKey domain.APIKey
+		if err := tx.Where("revoked_at IS NULL").First(&oldKey, id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&oldKey).Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		token, err := GenerateToken()
+		if err != nil {
+			return err
+		}
+
+		newKey = &domain.APIKey{
+			Name:  oldKey.Name,
+			Token: token,
+			Role:  oldKey.Role,
+		}
+		return tx.Create(newKey).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newKey, nil
+}
+
+// GenerateToken は40文字のランダムなトークンを生成する
+func GenerateToken() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -41,12 +41,32 @@ func (r *ArtworkRepository) GetByQRToken(token string) (*domain.Artwork, error)
 	return &artwork, nil
 }
 
+// GetAll は取り下げ中のものを除いたアートワークを返す
 func (r *ArtworkRepository) GetAll() ([]domain.Artwork, error) {
 	var artworks []domain.Artwork
-	err := r.db.Preload("Asset").Preload("User").Find(&artworks).Error
+	err := r.db.Preload("Asset").Preload("User").Where("taken_down_at IS NULL").Find(&artworks).Error
 	return artworks, err
 }
 
+// Like はQRトークンのアートワークのいいね数を1増やし、増やした後の数を返す
+// 却下・取り下げ中のアートワークには付けられない
+func (r *ArtworkRepository) Like(token string) (int, error) {
+	var likeCount int
+	result := r.db.Raw(
+		`UPDATE artworks SET like_count = like_count + 1
+		WHERE TRIM(qr_token) = ? AND status <> ? AND taken_down_at IS NULL
+		RETURNING like_count`,
+		strings.TrimSpace(token), domain.ArtworkStatusRejected,
+	).Scan(&likeCount)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return likeCount, nil
+}
+
 func (r *ArtworkRepository) Delete(id uint) error {
 	return r.db.Delete(&domain.Artwork{}, id).Error
 }
@@ -54,6 +74,7 @@ func (r *ArtworkRepository) Delete(id uint) error {
 func (r *ArtworkRepository) List(limit, offset int) ([]domain.Artwork, error) {
 	var artworks []domain.Artwork
 	err := r.db.Preload("Asset").Preload("User").
+		Where("taken_down_at IS NULL").
 		Order("created_at DESC").
 		Limit(limit).Offset(offset).
 		Find(&artworks).Error
@@ -90,20 +111,6 @@ func (r *AssetRepository) GetByID(id uint) (*domain.Asset, error) {
 	return &asset, nil
 }
 
-// アニメーション種類のラウンドロビン
-var animationKinds = []string{"pulsate", "disperse", "explode", "spin_fight", "stream_in"}
-var animationIndex = 0
-
-func GetNextAnimationKind() string {
-	// デフォルトは常に回転戦闘
-	return "spin_fight"
-
-	// ラウンドロビンを使用する場合は以下をコメント解除
-	// kind := animationKinds[animationIndex]
-	// animationIndex = (animationIndex + 1) % len(animationKinds)
-	// return kind
-}
-
 func GetRandomRNGSeed() int64 {
 	return rand.New(rand.NewSource(time.Now().UnixNano())).Int63()
 }
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditFilter は監査ログの絞り込み条件（ゼロ値の項目は条件にしない）
+type AuditFilter struct {
+	From *time.Time
+	To   *time.Time
+	// APIキーの名前またはID
+	Actor  string
+	Role   string
+	Method string
+	Status int
+	// すべて含むイベントだけを返す（{"scene_id": [3]}）
+	Targets map[string][]interface{}
+	// このIDより古いイベントだけを返す（ページング用）
+	BeforeID uint
+	Limit    int
+}
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(event *domain.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// DeleteBefore はcutoffより前のイベントを削除し、削除した件数を返す
+func (r *AuditRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&domain.AuditEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// Query は条件に合うイベントを新しい順に返す
+func (r *AuditRepository) Query(filter AuditFilter) ([]domain.AuditEvent, error) {
+	query := r.db.Model(&domain.AuditEvent{})
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Actor != "" {
+		query = query.Where("api_key_name = ? OR CAST(api_key_id AS TEXT) = ?", filter.Actor, filter.Actor)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Method != "" {
+		query = query.Where("method = ?", filter.Method)
+	}
+	if filter.Status != 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if len(filter.Targets) > 0 {
+		targets, err := json.Marshal(filter.Targets)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("targets @> ?::jsonb", string(targets))
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	var events []domain.AuditEvent
+	err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&events).Error
+	return events, err
+}
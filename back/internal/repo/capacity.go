@@ -0,0 +1,207 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pg_advisory_xact_lockの名前空間（第2引数にシーンIDを使う）
+const capacityLockNamespace = 20
+
+// CapacityChanges はEnforceで退場・復帰したエンティティ
+type CapacityChanges struct {
+	Evicted []domain.SceneEntity
+	// rotateのキューから戻したエンティティ（Artworkをプリロード済み）
+	Restored []domain.SceneEntity
+}
+
+type CapacityRepository struct {
+	db *gorm.DB
+}
+
+func NewCapacityRepository(db *gorm.DB) *CapacityRepository {
+	return &CapacityRepository{db: db}
+}
+
+// ValidateEvictionPolicy は定員と退場ポリシーを検証する
+func ValidateEvictionPolicy(maxEntities int, policy string) error {
+	if maxEntities < 0 {
+		return errors.New("max_entities must be >= 0")
+	}
+	switch policy {
+	case domain.EvictionPolicyOldest, domain.EvictionPolicyLeastLiked, domain.EvictionPolicyRandom, domain.EvictionPolicyRotate:
+		return nil
+	default:
+		return fmt.Errorf("unknown eviction policy: %s", policy)
+	}
+}
+
+func (r *CapacityRepository) Update(sceneID uint, maxEntities int, policy string) error {
+	return r.db.Model(&domain.Scene{}).Where("id = ?", sceneID).Updates(map[string]interface{}{
+		"max_entities":    maxEntities,
+		"eviction_policy": policy,
+	}).Error
+}
+
+// Enforce はシーンのエンティティ数を定員に合わせる
+//   - 定員を超えていれば、keep以外からポリシーに従って超過分を退場させる（rotateならキューへ退避）
+//   - 空きがあればキューに退避したエンティティを古い順に戻す
+//
+// シーン単位のアドバイザリロックで直列化するので、同時にアップロードされても定員を超えて残らない
+func (r *CapacityRepository) Enforce(sceneID uint, keep []uint) (*CapacityChanges, error) {
+	changes := &CapacityChanges{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", capacityLockNamespace, sceneID).Error; err != nil {
+			return err
+		}
+
+		var scene domain.Scene
+		if err := tx.First(&scene, sceneID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&domain.SceneEntity{}).Where("scene_id = ?", sceneID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		if scene.MaxEntities > 0 && int(count) > scene.MaxEntities {
+			evicted, err := evict(tx, &scene, int(count)-scene.MaxEntities, keep)
+			if err != nil {
+				return err
+			}
+			changes.Evicted = evicted
+			return nil
+		}
+
+		free := -1
+		if scene.MaxEntities > 0 {
+			free = scene.MaxEntities - int(count)
+		}
+		restored, err := restoreQueued(tx, sceneID, free)
+		if err != nil {
+			return err
+		}
+		changes.Restored = restored
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func evict(tx *gorm.DB, scene *domain.Scene, n int, keep []uint) ([]domain.SceneEntity, error) {
+	query := tx.Model(&domain.SceneEntity{}).Where("scene_entities.scene_id = ?", scene.ID)
+	if len(keep) > 0 {
+		query = query.Where("scene_entities.id NOT IN ?", keep)
+	}
+	switch scene.EvictionPolicy {
+	case domain.EvictionPolicyLeastLiked:
+		// 同じいいね数なら古い方から
+		query = query.Joins("JOIN artworks ON artworks.id = scene_entities.artwork_id").
+			Order("artworks.like_count, scene_entities.created_at, scene_entities.id")
+	case domain.EvictionPolicyRandom:
+		query = query.Order("random()")
+	default:
+		query = query.Order("scene_entities.created_at, scene_entities.id")
+	}
+
+	var victims []domain.SceneEntity
+	if err := query.Select("scene_entities.*").Limit(n).Find(&victims).Error; err != nil {
+		return nil, err
+	}
+	if len(victims) == 0 {
+		return nil, nil
+	}
+
+	if scene.EvictionPolicy == domain.EvictionPolicyRotate {
+		now := time.Now()
+		for _, entity := range victims {
+			queued := domain.QueuedEntity{
+				SceneID:         entity.SceneID,
+				ArtworkID:       entity.ArtworkID,
+				InitX:           entity.InitX,
+				InitY:           entity.InitY,
+				InitVX:          entity.InitVX,
+				InitVY:          entity.InitVY,
+				InitAngle:       entity.InitAngle,
+				InitScale:       entity.InitScale,
+				AnimationKind:   entity.AnimationKind,
+				AnimationParams: entity.AnimationParams,
+				RNGSeed:         entity.RNGSeed,
+				QueuedAt:        now,
+			}
+			if err := tx.Create(&queued).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ids := make([]uint, 0, len(victims))
+	for _, entity := range victims {
+		ids = append(ids, entity.ID)
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&domain.SceneEntity{}).Error; err != nil {
+		return nil, err
+	}
+	return victims, nil
+}
+
+// restoreQueued はキューの先頭からlimit件（負なら全件）をエンティティに戻す
+func restoreQueued(tx *gorm.DB, sceneID uint, limit int) ([]domain.SceneEntity, error) {
+	if limit == 0 {
+		return nil, nil
+	}
+
+	var queued []domain.QueuedEntity
+	if err := tx.Where("scene_id = ?", sceneID).Order("queued_at, id").Limit(limit).Find(&queued).Error; err != nil {
+		return nil, err
+	}
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(queued))
+	for _, q := range queued {
+		entity := domain.SceneEntity{
+			SceneID:         q.SceneID,
+			ArtworkID:       q.ArtworkID,
+			InitX:           q.InitX,
+			InitY:           q.InitY,
+			InitVX:          q.InitVX,
+			InitVY:          q.InitVY,
+			InitAngle:       q.InitAngle,
+			InitScale:       q.InitScale,
+			AnimationKind:   q.AnimationKind,
+			AnimationParams: q.AnimationParams,
+			RNGSeed:         q.RNGSeed,
+		}
+		if err := tx.Omit(clause.Associations).Create(&entity).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, entity.ID)
+	}
+
+	if err := tx.Delete(&queued).Error; err != nil {
+		return nil, err
+	}
+
+	var restored []domain.SceneEntity
+	if err := tx.Preload("Artwork").Where("id IN ?", ids).Order("id").Find(&restored).Error; err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// ListQueued はキューに退避中のエンティティを戻る順に返す
+func (r *CapacityRepository) ListQueued(sceneID uint) ([]domain.QueuedEntity, error) {
+	var queued []domain.QueuedEntity
+	err := r.db.Where("scene_id = ?", sceneID).Order("queued_at, id").Find(&queued).Error
+	return queued, err
+}
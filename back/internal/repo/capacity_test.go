@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"testing"
+)
+
+func TestValidateEvictionPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxEntities int
+		policy      string
+		wantErr     bool
+	}{
+		{name: "無制限", maxEntities: 0, policy: domain.EvictionPolicyOldest},
+		{name: "oldest", maxEntities: 10, policy: domain.EvictionPolicyOldest},
+		{name: "least_liked", maxEntities: 10, policy: domain.EvictionPolicyLeastLiked},
+		{name: "random", maxEntities: 10, policy: domain.EvictionPolicyRandom},
+		{name: "rotate", maxEntities: 10, policy: domain.EvictionPolicyRotate},
+		{name: "負の定員", maxEntities: -1, policy: domain.EvictionPolicyOldest, wantErr: true},
+		{name: "未知のポリシー", maxEntities: 10, policy: "newest", wantErr: true},
+		{name: "ポリシーなし", maxEntities: 10, policy: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEvictionPolicy(tt.maxEntities, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEvictionPolicy(%d, %q) error = %v, wantErr %v", tt.maxEntities, tt.policy, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCapacityEnforceEvicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		maxEntities int
+		// 古い順に作るエンティティのアートワークのいいね数
+		likes []int
+		// 残すエンティティ（likesの添字）
+		keep []int
+		// 退場するエンティティ（likesの添字、randomは数だけ確かめる）
+		wantEvicted []int
+		wantQueued  int
+	}{
+		{name: "定員内なら何もしない", policy: domain.EvictionPolicyOldest, maxEntities: 3, likes: []int{0, 0, 0}},
+		{name: "無制限", policy: domain.EvictionPolicyOldest, maxEntities: 0, likes: []int{0, 0, 0}},
+		{name: "oldestは古い順", policy: domain.EvictionPolicyOldest, maxEntities: 1, likes: []int{0, 0, 0}, wantEvicted: []int{0, 1}},
+		{name: "keepは退場させない", policy: domain.EvictionPolicyOldest, maxEntities: 2, likes: []int{0, 0, 0}, keep: []int{0}, wantEvicted: []int{1}},
+		{name: "least_likedはいいねの少ない順", policy: domain.EvictionPolicyLeastLiked, maxEntities: 2, likes: []int{5, 1, 3}, wantEvicted: []int{1}},
+		{name: "least_likedの同数は古い順", policy: domain.EvictionPolicyLeastLiked, maxEntities: 1, likes: []int{2, 2, 5}, wantEvicted: []int{0, 1}},
+		{name: "random", policy: domain.EvictionPolicyRandom, maxEntities: 1, likes: []int{0, 0, 0}, wantEvicted: []int{-1, -1}},
+		{name: "rotateはキューへ退避", policy: domain.EvictionPolicyRotate, maxEntities: 2, likes: []int{0, 0, 0}, wantEvicted: []int{0}, wantQueued: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			scene := createTestScene(t, db, domain.Scene{MaxEntities: tt.maxEntities, EvictionPolicy: tt.policy})
+			entities := make([]uint, len(tt.likes))
+			for i, likes := range tt.likes {
+				artwork := createTestArtwork(t, db, likes)
+				entities[i] = createTestEntity(t, db, scene.ID, artwork.ID, "pulsate").ID
+			}
+			var keep []uint
+			for _, i := range tt.keep {
+				keep = append(keep, entities[i])
+			}
+
+			changes, err := NewCapacityRepository(db).Enforce(scene.ID, keep)
+			if err != nil {
+				t.Fatalf("Enforce() error = %v", err)
+			}
+
+			got := entityIDs(changes.Evicted)
+			if len(got) != len(tt.wantEvicted) {
+				t.Fatalf("evicted %v, want %d entities", got, len(tt.wantEvicted))
+			}
+			if tt.policy != domain.EvictionPolicyRandom {
+				var want []uint
+				for _, i := range tt.wantEvicted {
+					want = append(want, entities[i])
+				}
+				if !sameIDs(got, want) {
+					t.Errorf("evicted %v, want %v", got, want)
+				}
+			}
+
+			var remaining int64
+			if err := db.Model(&domain.SceneEntity{}).Where("scene_id = ?", scene.ID).Count(&remaining).Error; err != nil {
+				t.Fatal(err)
+			}
+			if want := len(tt.likes) - len(tt.wantEvicted); int(remaining) != want {
+				t.Errorf("remaining entities = %d, want %d", remaining, want)
+			}
+
+			queued, err := NewCapacityRepository(db).ListQueued(scene.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queued) != tt.wantQueued {
+				t.Errorf("queued = %d, want %d", len(queued), tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestCapacityEnforceRestoresQueued(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxEntities int
+		queued      int
+		// 退避後に削除するエンティティの数
+		removed      int
+		wantRestored int
+	}{
+		{name: "空きがなければ戻さない", maxEntities: 2, queued: 2, removed: 0, wantRestored: 0},
+		{name: "空きの分だけ古い順に戻す", maxEntities: 2, queued: 2, removed: 1, wantRestored: 1},
+		{name: "キューより空きが多い", maxEntities: 2, queued: 1, removed: 2, wantRestored: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			capacity := NewCapacityRepository(db)
+			scene := createTestScene(t, db, domain.Scene{MaxEntities: tt.maxEntities, EvictionPolicy: domain.EvictionPolicyRotate})
+
+			var entities []uint
+			for i := 0; i < tt.maxEntities+tt.queued; i++ {
+				artwork := createTestArtwork(t, db, 0)
+				entities = append(entities, createTestEntity(t, db, scene.ID, artwork.ID, "pulsate").ID)
+			}
+			changes, err := capacity.Enforce(scene.ID, nil)
+			if err != nil {
+				t.Fatalf("Enforce() error = %v", err)
+			}
+			if len(changes.Evicted) != tt.queued {
+				t.Fatalf("evicted %d, want %d", len(changes.Evicted), tt.queued)
+			}
+			queuedArtworks := make([]uint, 0, len(changes.Evicted))
+			for _, entity := range changes.Evicted {
+				queuedArtworks = append(queuedArtworks, entity.ArtworkID)
+			}
+
+			// 表示中のエンティティを新しい方から削除して空きを作る
+			for i := 0; i < tt.removed; i++ {
+				if err := db.Delete(&domain.SceneEntity{}, entities[len(entities)-1-i]).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changes, err = capacity.Enforce(scene.ID, nil)
+			if err != nil {
+				t.Fatalf("Enforce() error = %v", err)
+			}
+			if len(changes.Evicted) != 0 {
+				t.Errorf("evicted %v while restoring", entityIDs(changes.Evicted))
+			}
+			if len(changes.Restored) != tt.wantRestored {
+				t.Fatalf("restored %d, want %d", len(changes.Restored), tt.wantRestored)
+			}
+			for i, entity := range changes.Restored {
+				if entity.ArtworkID != queuedArtworks[i] || entity.Artwork.ID != entity.ArtworkID {
+					t.Errorf("restored[%d] artwork = %d (preloaded %d), want %d", i, entity.ArtworkID, entity.Artwork.ID, queuedArtworks[i])
+				}
+			}
+
+			queued, err := capacity.ListQueued(scene.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tt.queued - tt.wantRestored; len(queued) != want {
+				t.Errorf("queued = %d, want %d", len(queued), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SceneClockRepository はルームごとの基準時刻（clock.syncのt0）をRedisで管理する
+// 全インスタンスが同じt0を送れるよう、プロセス内ではなくRedisに置く
+type SceneClockRepository struct {
+	rdb *redis.Client
+}
+
+func NewSceneClockRepository(redisClient *RedisClient) *SceneClockRepository {
+	return &SceneClockRepository{rdb: redisClient.Client}
+}
+
+func sceneEpochKey(room string) string {
+	return fmt.Sprintf("scene_epoch:%s", room)
+}
+
+// Epoch はルームの基準時刻を返す。未設定なら現在時刻で初期化する（先に設定したインスタンスの値が残る）
+func (r *SceneClockRepository) Epoch(room string) (time.Time, error) {
+	ctx := context.Background()
+	key := sceneEpochKey(room)
+	if err := r.rdb.SetNX(ctx, key, time.Now().UnixMilli(), 0).Err(); err != nil {
+		return time.Time{}, err
+	}
+	ms, err := r.rdb.Get(ctx, key).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// Reset はルームの基準時刻を現在時刻に戻す
+func (r *SceneClockRepository) Reset(room string) (time.Time, error) {
+	now := time.Now()
+	if err := r.rdb.Set(context.Background(), sceneEpochKey(room), now.UnixMilli(), 0).Err(); err != nil {
+		return time.Time{}, err
+	}
+	return now, nil
+}
+
+// Delete はルームの基準時刻を消す
+func (r *SceneClockRepository) Delete(room string) error {
+	return r.rdb.Del(context.Background(), sceneEpochKey(room)).Err()
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 報告が途絶えたエンティティの状態はこの時間で無効になる
+const EntityStateTTL = 60 * time.Second
+
+type EntityStateRepository struct {
+	rdb *redis.Client
+}
+
+func NewEntityStateRepository(redisClient *RedisClient) *EntityStateRepository {
+	return &EntityStateRepository{rdb: redisClient.Client}
+}
+
+func entityStateKey(sceneID uint) string {
+	return fmt.Sprintf("entity_state:%d", sceneID)
+}
+
+func (r *EntityStateRepository) Save(state *domain.EntityState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	key := entityStateKey(state.SceneID)
+	pipe := r.rdb.TxPipeline()
+	pipe.HSet(ctx, key, strconv.FormatUint(uint64(state.EntityID), 10), data)
+	pipe.Expire(ctx, key, EntityStateTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *EntityStateRepository) Get(sceneID, entityID uint) (*domain.EntityState, error) {
+	ctx := context.Background()
+	data, err := r.rdb.HGet(ctx, entityStateKey(sceneID), strconv.FormatUint(uint64(entityID), 10)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var state domain.EntityState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if time.Since(state.UpdatedAt) > EntityStateTTL {
+		return nil, redis.Nil
+	}
+	return &state, nil
+}
+
+// GetBySceneID はTTL内に報告されたエンティティ状態をentity_idごとに返す
+func (r *EntityStateRepository) GetBySceneID(sceneID uint) (map[uint]domain.EntityState, error) {
+	ctx := context.Background()
+	values, err := r.rdb.HGetAll(ctx, entityStateKey(sceneID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[uint]domain.EntityState, len(values))
+	for _, value := range values {
+		var state domain.EntityState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			continue
+		}
+		if time.Since(state.UpdatedAt) > EntityStateTTL {
+			continue
+		}
+		states[state.EntityID] = state
+	}
+	return states, nil
+}
+
+func (r *EntityStateRepository) Delete(sceneID, entityID uint) error {
+	ctx := context.Background()
+	return r.rdb.HDel(ctx, entityStateKey(sceneID), strconv.FormatUint(uint64(entityID), 10)).Err()
+}
+
+func (r *EntityStateRepository) DeleteBySceneID(sceneID uint) error {
+	ctx := context.Background()
+	return r.rdb.Del(ctx, entityStateKey(sceneID)).Err()
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pg_advisory_xact_lockの名前空間（第2引数にシーンIDを使う）
+const fightLockNamespace = 14
+
+// FightChanges はReconcileで開始・終了した対戦
+type FightChanges struct {
+	Started []domain.FightMatch
+	Ended   []domain.FightMatch
+}
+
+// FightPositionFunc はペアリングに使うエンティティの現在位置を返す
+type FightPositionFunc func(entity *domain.SceneEntity) (x, y float64)
+
+// FightPowerFunc は決着時のエンティティの勢いを返す。ディスプレイからの報告がなければokはfalse
+type FightPowerFunc func(entity *domain.SceneEntity) (power float64, ok bool)
+
+type FightRepository struct {
+	db *gorm.DB
+}
+
+func NewFightRepository(db *gorm.DB) *FightRepository {
+	return &FightRepository{db: db}
+}
+
+// Reconcile はシーンの対戦を現在のエンティティに合わせる
+//   - 相手が消えた（またはspin_fightでなくなった）対戦は勝者なしで終了
+//   - 時間切れの対戦は勢い（power）が大きい方を勝者として終了（decideWinner）
+//   - 待機中のspin_fightエンティティを近い順にペアにする
+//
+// シーン単位のアドバイザリロックで直列化するので、複数インスタンスから呼んでも二重に割り当てない
+func (r *FightRepository) Reconcile(
+	sceneID uint,
+	duration, cooldown time.Duration,
+	position FightPositionFunc,
+	power FightPowerFunc,
+) (*FightChanges, error) {
+	changes := &FightChanges{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", fightLockNamespace, sceneID).Error; err != nil {
+			return err
+		}
+
+		var entities []domain.SceneEntity
+		if err := tx.Where("scene_id = ? AND animation_kind = ?", sceneID, "spin_fight").Order("id").Find(&entities).Error; err != nil {
+			return err
+		}
+		fighters := make(map[uint]*domain.SceneEntity, len(entities))
+		for i := range entities {
+			fighters[entities[i].ID] = &entities[i]
+		}
+
+		var active []domain.FightMatch
+		if err := tx.Where("scene_id = ? AND ended_at IS NULL", sceneID).Find(&active).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		busy := make(map[uint]bool)
+		for i := range active {
+			match := &active[i]
+			a, okA := fighters[match.EntityAID]
+			b, okB := fighters[match.EntityBID]
+
+			switch {
+			case !okA || !okB:
+				match.EndReason = domain.FightEndPartnerRemoved
+			case !now.Before(match.EndsAt):
+				match.EndReason = domain.FightEndFinished
+				winner := decideWinner(a, b, power)
+				match.WinnerEntityID = &winner.ID
+				match.WinnerArtworkID = &winner.ArtworkID
+			default:
+				busy[match.EntityAID] = true
+				busy[match.EntityBID] = true
+				continue
+			}
+
+			match.EndedAt = &now
+			if err := tx.Save(match).Error; err != nil {
+				return err
+			}
+			changes.Ended = append(changes.Ended, *match)
+		}
+
+		// 終わったばかりのエンティティは少し休ませる（同じ相手と即再戦しないように）
+		var resting []domain.FightMatch
+		if err := tx.Where("scene_id = ? AND end_reason = ? AND ended_at > ?", sceneID, domain.FightEndFinished, now.Add(-cooldown)).
+			Find(&resting).Error; err != nil {
+			return err
+		}
+		for _, match := range resting {
+			busy[match.EntityAID] = true
+			busy[match.EntityBID] = true
+		}
+
+		var waiting []*domain.SceneEntity
+		for i := range entities {
+			if !busy[entities[i].ID] {
+				waiting = append(waiting, &entities[i])
+			}
+		}
+
+		for _, pair := range nearestPairs(waiting, position) {
+			match := domain.FightMatch{
+				SceneID:    sceneID,
+				EntityAID:  pair[0].ID,
+				EntityBID:  pair[1].ID,
+				ArtworkAID: pair[0].ArtworkID,
+				ArtworkBID: pair[1].ArtworkID,
+				StartedAt:  now,
+				EndsAt:     now.Add(duration),
+			}
+			if err := tx.Create(&match).Error; err != nil {
+				return err
+			}
+			changes.Started = append(changes.Started, match)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// decideWinner は決着時の勢いが大きい方を勝者にする
+// どちらかの状態が報告されていない（表示しているディスプレイがない）か同じ勢いの場合だけランダムに決める
+func decideWinner(a, b *domain.SceneEntity, power FightPowerFunc) *domain.SceneEntity {
+	powerA, okA := power(a)
+	powerB, okB := power(b)
+	switch {
+	case okA && okB && powerA > powerB:
+		return a
+	case okA && okB && powerB > powerA:
+		return b
+	case rand.Intn(2) == 0:
+		return a
+	default:
+		return b
+	}
+}
+
+// nearestPairs は距離の近い組から貪欲にペアを作る。奇数なら1体は待機のまま
+func nearestPairs(entities []*domain.SceneEntity, position FightPositionFunc) [][2]*domain.SceneEntity {
+	type candidate struct {
+		i, j     int
+		distance float64
+	}
+
+	xs := make([]float64, len(entities))
+	ys := make([]float64, len(entities))
+	for i, entity := range entities {
+		xs[i], ys[i] = position(entity)
+	}
+
+	candidates := make([]candidate, 0, len(entities)*(len(entities)-1)/2)
+	for i := range entities {
+		for j := i + 1; j < len(entities); j++ {
+			candidates = append(candidates, candidate{i, j, math.Hypot(xs[i]-xs[j], ys[i]-ys[j])})
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].distance < candidates[b].distance
+	})
+
+	paired := make([]bool, len(entities))
+	var pairs [][2]*domain.SceneEntity
+	for _, c := range candidates {
+		if paired[c.i] || paired[c.j] {
+			continue
+		}
+		paired[c.i] = true
+		paired[c.j] = true
+		pairs = append(pairs, [2]*domain.SceneEntity{entities[c.i], entities[c.j]})
+	}
+	return pairs
+}
+
+func (r *FightRepository) ListActiveBySceneID(sceneID uint) ([]domain.FightMatch, error) {
+	var matches []domain.FightMatch
+	err := r.db.Where("scene_id = ? AND ended_at IS NULL", sceneID).Order("id").Find(&matches).Error
+	return matches, err
+}
+
+// SceneIDsToReconcile はspin_fightエンティティか進行中の対戦があるシーンを返す
+func (r *FightRepository) SceneIDsToReconcile() ([]uint, error) {
+	var ids []uint
+	err := r.db.Raw(`
+		SELECT scene_id FROM scene_entities WHERE animation_kind = 'spin_fight'
+		UNION
+		SELECT scene_id FROM fight_matches WHERE ended_at IS NULL
+	`).Scan(&ids).Error
+	return ids, err
+}
+
+// Leaderboard は決着した対戦の勝利数でアートワークを並べる（sceneIDが0なら全シーン）
+// 勝敗はディスプレイが報告した決着時の勢い（速さ×大きさ）で決まる（decideWinner）
+// 却下・取り下げ中のアートワークは載せない
+func (r *FightRepository) Leaderboard(sceneID uint, limit int) ([]domain.FightLeaderboardEntry, error) {
+	query := r.db.Table("fight_matches AS m").
+		Select(`a.id AS artwork_id, TRIM(a.qr_token) AS qr_token,
+			COUNT(*) FILTER (WHERE m.winner_artwork_id = a.id) AS wins,
+			COUNT(*) AS fights`).
+		Joins("JOIN artworks AS a ON a.id IN (m.artwork_a_id, m.artwork_b_id)").
+		Where("m.end_reason = ?", domain.FightEndFinished).
+		Where("a.status <> ? AND a.taken_down_at IS NULL", domain.ArtworkStatusRejected)
+	if sceneID != 0 {
+		query = query.Where("m.scene_id = ?", sceneID)
+	}
+
+	var entries []domain.FightLeaderboardEntry
+	err := query.Group("a.id, a.qr_token").
+		Order("wins DESC, fights ASC, a.id").
+		Limit(limit).
+		Scan(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"reflect"
+	"testing"
+)
+
+func TestNearestPairs(t *testing.T) {
+	tests := []struct {
+		name string
+		// エンティティの位置（IDは添字+1）
+		positions [][2]float64
+		want      [][2]uint
+	}{
+		{name: "空", positions: nil, want: nil},
+		{name: "1体は待機", positions: [][2]float64{{0, 0}}, want: nil},
+		{name: "2体", positions: [][2]float64{{0, 0}, {100, 0}}, want: [][2]uint{{1, 2}}},
+		{
+			name:      "近い組から",
+			positions: [][2]float64{{0, 0}, {500, 0}, {10, 0}, {510, 0}},
+			want:      [][2]uint{{1, 3}, {2, 4}},
+		},
+		{
+			name:      "奇数なら最も遠い1体が残る",
+			positions: [][2]float64{{0, 0}, {1000, 1000}, {0, 5}},
+			want:      [][2]uint{{1, 3}},
+		},
+		{
+			name:      "貪欲に組む（全体の距離の合計は最小とは限らない）",
+			positions: [][2]float64{{0, 0}, {3, 0}, {5, 0}, {9, 0}},
+			want:      [][2]uint{{2, 3}, {1, 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities := make([]*domain.SceneEntity, len(tt.positions))
+			for i, p := range tt.positions {
+				entities[i] = &domain.SceneEntity{ID: uint(i + 1), InitX: p[0], InitY: p[1]}
+			}
+
+			pairs := nearestPairs(entities, func(entity *domain.SceneEntity) (float64, float64) {
+				return entity.InitX, entity.InitY
+			})
+
+			var got [][2]uint
+			for _, pair := range pairs {
+				got = append(got, [2]uint{pair[0].ID, pair[1].ID})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nearestPairs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecideWinner(t *testing.T) {
+	a := &domain.SceneEntity{ID: 1}
+	b := &domain.SceneEntity{ID: 2}
+
+	tests := []struct {
+		name   string
+		powers map[uint]float64
+		// 0なら乱数で決まる（どちらかが返ればよい）
+		want uint
+	}{
+		{name: "aの勢いが強い", powers: map[uint]float64{1: 5, 2: 3}, want: 1},
+		{name: "bの勢いが強い", powers: map[uint]float64{1: 1, 2: 3}, want: 2},
+		{name: "同じ勢い", powers: map[uint]float64{1: 2, 2: 2}},
+		{name: "片方の報告がない", powers: map[uint]float64{1: 5}},
+		{name: "どちらも報告がない", powers: map[uint]float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			power := func(entity *domain.SceneEntity) (float64, bool) {
+				p, ok := tt.powers[entity.ID]
+				return p, ok
+			}
+			for i := 0; i < 20; i++ {
+				winner := decideWinner(a, b, power)
+				if winner != a && winner != b {
+					t.Fatalf("decideWinner() = %v, want a or b", winner)
+				}
+				if tt.want != 0 && winner.ID != tt.want {
+					t.Fatalf("decideWinner() = %d, want %d", winner.ID, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ModerationResult はDecideで状態を変えたアートワーク
+type ModerationResult struct {
+	ArtworkIDs []uint
+	// 承認したアートワークを追加するシーン
+	Placements []domain.PendingPlacement
+}
+
+type ModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+// AddPending は承認後にアートワークを追加するシーンを記録する
+func (r *ModerationRepository) AddPending(artworkID uint, sceneIDs []uint) error {
+	if len(sceneIDs) == 0 {
+		return nil
+	}
+	placements := make([]domain.PendingPlacement, 0, len(sceneIDs))
+	for _, sceneID := range sceneIDs {
+		placements = append(placements, domain.PendingPlacement{ArtworkID: artworkID, SceneID: sceneID})
+	}
+	return r.db.Create(&placements).Error
+}
+
+// ListPending は承認待ちのアートワークを古い順に返す（取り下げ中のものは除く）
+func (r *ModerationRepository) ListPending() ([]domain.Artwork, error) {
+	var artworks []domain.Artwork
+	err := r.db.Preload("Asset").Preload("PendingPlacements").
+		Where("status = ? AND taken_down_at IS NULL", domain.ArtworkStatusPending).
+		Order("created_at, id").
+		Find(&artworks).Error
+	return artworks, err
+}
+
+// Decide は承認待ちのアートワークを承認・却下する。承認待ちでないもの・取り下げ中のものは変更しない
+// 承認待ちの追加先は取り出して返し（却下なら捨てる）、却下ならrotateのキューからも外す
+func (r *ModerationRepository) Decide(artworkIDs []uint, status string, reason *string, moderatedBy *uint) (*ModerationResult, error) {
+	result := &ModerationResult{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Artwork{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND status = ? AND taken_down_at IS NULL", artworkIDs, domain.ArtworkStatusPending).
+			Order("id").
+			Pluck("id", &result.ArtworkIDs).Error; err != nil {
+			return err
+		}
+		if len(result.ArtworkIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&domain.Artwork{}).Where("id IN ?", result.ArtworkIDs).Updates(map[string]interface{}{
+			"status":            status,
+			"moderation_reason": reason,
+			"moderated_by":      moderatedBy,
+			"moderated_at":      time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("artwork_id IN ?", result.ArtworkIDs).Order("artwork_id, scene_id").Find(&result.Placements).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("artwork_id IN ?", result.ArtworkIDs).Delete(&domain.PendingPlacement{}).Error; err != nil {
+			return err
+		}
+
+		if status == domain.ArtworkStatusRejected {
+			result.Placements = nil
+			if err := tx.Where("artwork_id IN ?", result.ArtworkIDs).Delete(&domain.QueuedEntity{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const roomChannelPrefix = "room:"
+
+// RoomPubSub はRedis pub/subでルーム宛てのメッセージを全バックエンドインスタンスに配信する
+// チャンネル名は room:{room}（例: room:scene:1）
+type RoomPubSub struct {
+	rdb *redis.Client
+}
+
+func NewRoomPubSub(redisClient *RedisClient) *RoomPubSub {
+	return &RoomPubSub{rdb: redisClient.Client}
+}
+
+func (p *RoomPubSub) Publish(room string, payload []byte) error {
+	return p.rdb.Publish(context.Background(), roomChannelPrefix+room, payload).Err()
+}
+
+// Subscribe は全ルームのチャンネルを購読し、受信したメッセージをdeliverに渡す
+// 接続が切れてもgo-redisが自動で再購読する
+func (p *RoomPubSub) Subscribe(deliver func(room string, payload []byte)) {
+	ctx := context.Background()
+	pubsub := p.rdb.PSubscribe(ctx, roomChannelPrefix+"*")
+	defer pubsub.Close()
+
+	log.Println("Subscribed to Redis room channels")
+	for msg := range pubsub.Channel() {
+		deliver(strings.TrimPrefix(msg.Channel, roomChannelPrefix), []byte(msg.Payload))
+	}
+}
+
+// publishSceneEventScript はシーンのリビジョンを進め、そのrevを付けたメッセージを配信する
+// スクリプトは他のコマンドと並行しないので、複数インスタンスから配信してもrev順に届く
+// ARGV: チャンネル, シーンID, revを含まないメッセージのJSON（{"type": ...}）
+var publishSceneEventScript = redis.NewScript(`
+local rev = redis.call('INCR', KEYS[1])
+local data = '{"rev":' .. rev .. ',' .. string.sub(ARGV[3], 2)
+redis.call('PUBLISH', ARGV[1], '{"scene_id":' .. ARGV[2] .. ',"seq":' .. rev .. ',"data":' .. data .. '}')
+return rev
+`)
+
+// PublishSceneEvent はシーンのリビジョンの採番と配信をまとめて行う（ws.SceneEventPublisher）
+func (p *RoomPubSub) PublishSceneEvent(room string, sceneID uint, message []byte) (int64, error) {
+	if len(message) <= 2 || message[0] != '{' {
+		return 0, errors.New("scene event must be a JSON object")
+	}
+	return publishSceneEventScript.Run(context.Background(), p.rdb,
+		[]string{sceneRevisionKey(sceneID)},
+		roomChannelPrefix+room, sceneID, string(message),
+	).Int64()
+}
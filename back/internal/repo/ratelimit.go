@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimit はトークンバケットの設定（PerMinuteが0なら制限しない）
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.PerMinute > 0
+}
+
+// RateBucket は消費するバケットのキーと設定
+type RateBucket struct {
+	Key   string
+	Limit RateLimit
+}
+
+// takeTokensScript はすべてのバケットに1トークン以上あるときだけ1ずつ消費する
+// 足りなければどれも消費せず、全バケットが1トークン以上になるまでの秒数を返す
+// 時刻はRedisのTIMEを使うので、複数インスタンスの時計がずれていても同じ結果になる
+var takeTokensScript = redis.NewScript(`
+redis.replicate_commands()
+local now = redis.call('TIME')
+local t = tonumber(now[1]) + tonumber(now[2]) / 1000000
+local tokens = {}
+local wait = 0
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[i * 2 - 1])
+  local rate = tonumber(ARGV[i * 2])
+  local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+  local n = tonumber(bucket[1]) or capacity
+  local ts = tonumber(bucket[2]) or t
+  n = math.min(capacity, n + math.max(0, t - ts) * rate)
+  tokens[i] = n
+  if n < 1 then
+    wait = math.max(wait, (1 - n) / rate)
+  end
+end
+for i, key in ipairs(KEYS) do
+  local capacity = tonumber(ARGV[i * 2 - 1])
+  local rate = tonumber(ARGV[i * 2])
+  local n = tokens[i]
+  if wait == 0 then
+    n = n - 1
+  end
+  redis.call('HSET', key, 'tokens', tostring(n), 'ts', tostring(t))
+  redis.call('PEXPIRE', key, math.ceil(capacity / rate * 1000) + 1000)
+end
+return tostring(wait)
+`)
+
+// RateLimiter はRedis上のトークンバケットでリクエスト数を制限する
+// 状態をRedisに置くので、再起動しても複数インスタンスでも同じ上限が効く
+type RateLimiter struct {
+	rdb *redis.Client
+}
+
+func NewRateLimiter(redisClient *RedisClient) *RateLimiter {
+	return &RateLimiter{rdb: redisClient.Client}
+}
+
+func rateLimitKey(key string) string {
+	return fmt.Sprintf("ratelimit:%s", key)
+}
+
+// Take は各バケットから1トークンずつ消費する
+// 制限に達していればfalseと、再試行できるまでの時間を返す
+func (r *RateLimiter) Take(buckets ...RateBucket) (bool, time.Duration, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]interface{}, 0, len(buckets)*2)
+	for _, bucket := range buckets {
+		if !bucket.Limit.Enabled() {
+			continue
+		}
+		burst := bucket.Limit.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		keys = append(keys, rateLimitKey(bucket.Key))
+		args = append(args, burst, float64(bucket.Limit.PerMinute)/60)
+	}
+	if len(keys) == 0 {
+		return true, 0, nil
+	}
+
+	result, err := takeTokensScript.Run(context.Background(), r.rdb, keys, args...).Text()
+	if err != nil {
+		return false, 0, err
+	}
+	wait, err := strconv.ParseFloat(result, 64)
+	if err != nil {
+		return false, 0, err
+	}
+	if wait <= 0 {
+		return true, 0, nil
+	}
+	return false, time.Duration(math.Ceil(wait*1000)) * time.Millisecond, nil
+}
+
+// TakeOnce はキーごとにttlの間1回だけtrueを返す（同じクライアントの重複操作を防ぐ）
+func (r *RateLimiter) TakeOnce(key string, ttl time.Duration) (bool, error) {
+	return r.rdb.SetNX(context.Background(), rateLimitKey(key), 1, ttl).Result()
+}
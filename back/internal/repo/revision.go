@@ -0,0 +1,38 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SceneRevisionRepository はシーンごとの単調増加するリビジョン番号をRedisで管理する
+type SceneRevisionRepository struct {
+	rdb *redis.Client
+}
+
+func NewSceneRevisionRepository(redisClient *RedisClient) *SceneRevisionRepository {
+	return &SceneRevisionRepository{rdb: redisClient.Client}
+}
+
+func sceneRevisionKey(sceneID uint) string {
+	return fmt.Sprintf("scene_rev:%d", sceneID)
+}
+
+func (r *SceneRevisionRepository) Incr(sceneID uint) (int64, error) {
+	return r.rdb.Incr(context.Background(), sceneRevisionKey(sceneID)).Result()
+}
+
+func (r *SceneRevisionRepository) Get(sceneID uint) (int64, error) {
+	rev, err := r.rdb.Get(context.Background(), sceneRevisionKey(sceneID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return rev, err
+}
+
+// Delete はシーンのリビジョンを消す（シーン削除後に古い番号が残らないように）
+func (r *SceneRevisionRepository) Delete(sceneID uint) error {
+	return r.rdb.Del(context.Background(), sceneRevisionKey(sceneID)).Err()
+}
@@ -2,9 +2,13 @@ package repo
 
 import (
 	"culture-festival-backend/internal/domain"
+	"encoding/json"
 	"math/rand"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SceneRepository struct {
@@ -28,12 +32,184 @@ func (r *SceneRepository) GetByID(id uint) (*domain.Scene, error) {
 	return &scene, nil
 }
 
+// GetBasicByID はリレーションを読み込まずにシーンだけを取得する
+func (r *SceneRepository) GetBasicByID(id uint) (*domain.Scene, error) {
+	var scene domain.Scene
+	err := r.db.First(&scene, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &scene, nil
+}
+
+// Update はシーン自身の列だけを保存する
+func (r *SceneRepository) Update(scene *domain.Scene) error {
+	return r.db.Omit(clause.Associations).Save(scene).Error
+}
+
+// UpdateAndClamp はシーンを更新し、新しいサイズからはみ出すエンティティと退避キューの初期位置を
+// 同じトランザクションで範囲内に収める。位置を変えたエンティティのIDを返す
+func (r *SceneRepository) UpdateAndClamp(scene *domain.Scene) ([]uint, error) {
+	var clamped []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(scene).Error; err != nil {
+			return err
+		}
+
+		width, height := float64(scene.Width), float64(scene.Height)
+		err := tx.Raw(
+			"UPDATE scene_entities SET init_x = LEAST(init_x, ?), init_y = LEAST(init_y, ?) WHERE scene_id = ? AND (init_x > ? OR init_y > ?) RETURNING id",
+			width, height, scene.ID, width, height,
+		).Scan(&clamped).Error
+		if err != nil {
+			return err
+		}
+
+		// キューから戻すときに範囲外に出ないように
+		return tx.Exec(
+			"UPDATE queued_entities SET init_x = LEAST(init_x, ?), init_y = LEAST(init_y, ?) WHERE scene_id = ? AND (init_x > ? OR init_y > ?)",
+			width, height, scene.ID, width, height,
+		).Error
+	})
+	return clamped, err
+}
+
+// Delete はシーンのエンティティ・対戦記録・スナップショット・退避キュー・承認待ちの追加先を削除し、ディスプレイノードを切り離してからシーンを削除する
+// 切り離したノードを返す（接続中のディスプレイへの通知用）
+func (r *SceneRepository) Delete(id uint) ([]domain.DisplayNode, error) {
+	var nodes []domain.DisplayNode
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 対戦のペアリング（FightRepository.Reconcile）と同じロックで新しい対戦の作成を防ぐ
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", fightLockNamespace, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", id).Find(&nodes).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&domain.DisplayNode{}).Where("scene_id = ?", id).Update("scene_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", id).Delete(&domain.SceneEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", id).Delete(&domain.FightMatch{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", id).Delete(&domain.SceneSnapshot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", id).Delete(&domain.QueuedEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", id).Delete(&domain.PendingPlacement{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&domain.Scene{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range nodes {
+		nodes[i].SceneID = nil
+	}
+	return nodes, nil
+}
+
+// Clone はシーンの設定・エンティティ・ディスプレイ配置を新しいシーンに複製する
+// ディスプレイノードのdevice_keyは一意なので新しく発行する
+func (r *SceneRepository) Clone(id uint, name string) (*domain.Scene, error) {
+	var clone domain.Scene
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var source domain.Scene
+		if err := tx.Preload("Entities").Preload("DisplayNodes").First(&source, id).Error; err != nil {
+			return err
+		}
+
+		clone = domain.Scene{
+			Name:                  name,
+			Width:                 source.Width,
+			Height:                source.Height,
+			AnimationPolicy:       source.AnimationPolicy,
+			AnimationPolicyParams: source.AnimationPolicyParams,
+			MaxEntities:           source.MaxEntities,
+			EvictionPolicy:        source.EvictionPolicy,
+			RequireApproval:       source.RequireApproval,
+		}
+		if err := tx.Omit(clause.Associations).Create(&clone).Error; err != nil {
+			return err
+		}
+
+		for _, entity := range source.Entities {
+			entity.ID = 0
+			entity.SceneID = clone.ID
+			entity.CreatedAt = time.Time{}
+			if err := tx.Omit(clause.Associations).Create(&entity).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, node := range source.DisplayNodes {
+			deviceKey, err := GenerateToken()
+			if err != nil {
+				return err
+			}
+			node.ID = 0
+			node.SceneID = &clone.ID
+			node.DeviceKey = deviceKey
+			node.CreatedAt = time.Time{}
+			if err := tx.Omit(clause.Associations).Create(&node).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(clone.ID)
+}
+
+func (r *SceneRepository) UpdateAnimationPolicy(id uint, policy string, params *json.RawMessage) error {
+	return r.db.Model(&domain.Scene{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"animation_policy":        policy,
+		"animation_policy_params": params,
+	}).Error
+}
+
 func (r *SceneRepository) List() ([]domain.Scene, error) {
 	var scenes []domain.Scene
 	err := r.db.Find(&scenes).Error
 	return scenes, err
 }
 
+// ListUploadTargets はシーン指定のないアップロードの振り分け先を返す
+func (r *SceneRepository) ListUploadTargets() ([]domain.Scene, error) {
+	var scenes []domain.Scene
+	err := r.db.Where("accept_uploads").Order("id").Find(&scenes).Error
+	return scenes, err
+}
+
+// SetUploadTargets は振り分け先を指定したシーンだけに置き換える
+func (r *SceneRepository) SetUploadTargets(ids []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Scene{}).Where("accept_uploads").Update("accept_uploads", false).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&domain.Scene{}).Where("id IN ?", ids).Update("accept_uploads", true).Error
+	})
+}
+
 func (r *SceneRepository) AddEntity(entity *domain.SceneEntity) error {
 	return r.db.Create(entity).Error
 }
@@ -44,10 +220,6 @@ func (r *SceneRepository) GetEntitiesBySceneID(sceneID uint) ([]domain.SceneEnti
 	return entities, err
 }
 
-func (r *SceneRepository) ResetScene(sceneID uint) error {
-	return r.db.Where("scene_id = ?", sceneID).Delete(&domain.SceneEntity{}).Error
-}
-
 type SceneEntityRepository struct {
 	db *gorm.DB
 }
@@ -79,14 +251,29 @@ func (r *SceneEntityRepository) GetByID(id uint) (*domain.SceneEntity, error) {
 	return &entity, nil
 }
 
+func (r *SceneEntityRepository) GetByIDWithArtwork(id uint) (*domain.SceneEntity, error) {
+	var entity domain.SceneEntity
+	err := r.db.Preload("Artwork.Asset").First(&entity, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
 func (r *SceneEntityRepository) Update(entity *domain.SceneEntity) error {
-	return r.db.Save(entity).Error
+	return r.db.Omit(clause.Associations).Save(entity).Error
 }
 
 func (r *SceneEntityRepository) Delete(id uint) error {
 	return r.db.Delete(&domain.SceneEntity{}, id).Error
 }
 
+func (r *SceneEntityRepository) GetByArtworkID(artworkID uint) ([]domain.SceneEntity, error) {
+	var entities []domain.SceneEntity
+	err := r.db.Where("artwork_id = ?", artworkID).Find(&entities).Error
+	return entities, err
+}
+
 func (r *SceneEntityRepository) DeleteByArtworkID(artworkID uint) error {
 	return r.db.Where("artwork_id = ?", artworkID).Delete(&domain.SceneEntity{}).Error
 }
@@ -103,9 +290,20 @@ func (r *DisplayNodeRepository) Create(node *domain.DisplayNode) error {
 	return r.db.Create(node).Error
 }
 
+func (r *DisplayNodeRepository) GetByID(id uint) (*domain.DisplayNode, error) {
+	var node domain.DisplayNode
+	err := r.db.Preload("Scene").First(&node, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
 func (r *DisplayNodeRepository) GetByDeviceKey(deviceKey string) (*domain.DisplayNode, error) {
 	var node domain.DisplayNode
-	err := r.db.Preload("Scene").Where("device_key = ?", deviceKey).First(&node).Error
+	// device_keyはCHAR(40)なので末尾の空白をトリムして比較
+	trimmedKey := strings.TrimSpace(deviceKey)
+	err := r.db.Preload("Scene").Where("TRIM(device_key) = ?", trimmedKey).First(&node).Error
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +322,14 @@ func (r *DisplayNodeRepository) List() ([]domain.DisplayNode, error) {
 	return nodes, err
 }
 
+func (r *DisplayNodeRepository) Update(node *domain.DisplayNode) error {
+	return r.db.Omit("Scene").Save(node).Error
+}
+
+func (r *DisplayNodeRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.DisplayNode{}, id).Error
+}
+
 // ランダムな初期位置と速度を生成
 func GenerateRandomPositionAndVelocity(sceneWidth, sceneHeight int) (x, y, vx, vy float64) {
 	// 画面の中央付近に配置
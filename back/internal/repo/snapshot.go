@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SnapshotRestoreResult はRestoreで作り直したエンティティと揮発状態
+type SnapshotRestoreResult struct {
+	Entities []domain.SceneEntity
+	// 新しいエンティティIDに付け替えた状態
+	States []*domain.EntityState
+	// アートワークが削除済み・未承認・却下・取り下げ中で復元できなかった数
+	Skipped int
+}
+
+type SceneSnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSceneSnapshotRepository(db *gorm.DB) *SceneSnapshotRepository {
+	return &SceneSnapshotRepository{db: db}
+}
+
+// BuildSnapshot はエンティティと揮発状態からスナップショットを組み立てる（保存はしない）
+func BuildSnapshot(sceneID uint, name, kind string, entities []domain.SceneEntity, states map[uint]domain.EntityState) (*domain.SceneSnapshot, error) {
+	saved := make([]domain.SnapshotEntity, 0, len(entities))
+	for _, entity := range entities {
+		e := domain.SnapshotEntity{
+			ArtworkID:       entity.ArtworkID,
+			InitX:           entity.InitX,
+			InitY:           entity.InitY,
+			InitVX:          entity.InitVX,
+			InitVY:          entity.InitVY,
+			InitAngle:       entity.InitAngle,
+			InitScale:       entity.InitScale,
+			AnimationKind:   entity.AnimationKind,
+			AnimationParams: entity.AnimationParams,
+			RNGSeed:         entity.RNGSeed,
+		}
+		if state, ok := states[entity.ID]; ok {
+			e.State = &state
+		}
+		saved = append(saved, e)
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return nil, err
+	}
+	entitiesJSON := json.RawMessage(data)
+
+	return &domain.SceneSnapshot{
+		SceneID:     sceneID,
+		Name:        name,
+		Kind:        kind,
+		EntityCount: len(saved),
+		Entities:    &entitiesJSON,
+	}, nil
+}
+
+func (r *SceneSnapshotRepository) Create(snapshot *domain.SceneSnapshot) error {
+	return r.db.Create(snapshot).Error
+}
+
+// ArchiveAndClear はシーンの全エンティティ（rotateのキューに退避中のものも含む）をゴミ箱（kind=trash）に退避してから削除する
+// 退避と削除を同じトランザクションで行うので、途中で追加されたエンティティを取りこぼさない
+func (r *SceneSnapshotRepository) ArchiveAndClear(sceneID uint, states map[uint]domain.EntityState, ttl time.Duration) (*domain.SceneSnapshot, error) {
+	var trash *domain.SceneSnapshot
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var entities []domain.SceneEntity
+		if err := tx.Where("scene_id = ?", sceneID).Order("id").Find(&entities).Error; err != nil {
+			return err
+		}
+
+		// キューの分は取り消し時に画面へ戻し、定員の判定に任せる
+		var queued []domain.QueuedEntity
+		if err := tx.Where("scene_id = ?", sceneID).Order("queued_at, id").Find(&queued).Error; err != nil {
+			return err
+		}
+		for _, q := range queued {
+			entities = append(entities, domain.SceneEntity{
+				SceneID:         q.SceneID,
+				ArtworkID:       q.ArtworkID,
+				InitX:           q.InitX,
+				InitY:           q.InitY,
+				InitVX:          q.InitVX,
+				InitVY:          q.InitVY,
+				InitAngle:       q.InitAngle,
+				InitScale:       q.InitScale,
+				AnimationKind:   q.AnimationKind,
+				AnimationParams: q.AnimationParams,
+				RNGSeed:         q.RNGSeed,
+			})
+		}
+
+		now := time.Now()
+		snapshot, err := BuildSnapshot(sceneID, "reset "+now.Format("2006-01-02 15:04:05.000"), domain.SnapshotKindTrash, entities, states)
+		if err != nil {
+			return err
+		}
+		expiresAt := now.Add(ttl)
+		snapshot.ExpiresAt = &expiresAt
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("scene_id = ?", sceneID).Delete(&domain.SceneEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("scene_id = ?", sceneID).Delete(&domain.QueuedEntity{}).Error; err != nil {
+			return err
+		}
+		trash = snapshot
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trash, nil
+}
+
+// DeleteExpired は取り消し期限を過ぎたゴミ箱を削除する
+func (r *SceneSnapshotRepository) DeleteExpired() (int64, error) {
+	result := r.db.Where("kind = ? AND expires_at < ?", domain.SnapshotKindTrash, time.Now()).Delete(&domain.SceneSnapshot{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *SceneSnapshotRepository) GetByID(id uint) (*domain.SceneSnapshot, error) {
+	var snapshot domain.SceneSnapshot
+	err := r.db.First(&snapshot, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (r *SceneSnapshotRepository) ExistsByName(sceneID uint, name string) (bool, error) {
+	var count int64
+	err := r.db.Model(&domain.SceneSnapshot{}).Where("scene_id = ? AND name = ?", sceneID, name).Count(&count).Error
+	return count > 0, err
+}
+
+// ListBySceneID は指定した種類のスナップショットをエンティティ本体を除いて新しい順に返す
+func (r *SceneSnapshotRepository) ListBySceneID(sceneID uint, kind string) ([]domain.SceneSnapshot, error) {
+	var snapshots []domain.SceneSnapshot
+	err := r.db.Omit("entities").Where("scene_id = ? AND kind = ?", sceneID, kind).Order("created_at DESC").Find(&snapshots).Error
+	return snapshots, err
+}
+
+func (r *SceneSnapshotRepository) Delete(id uint) error {
+	return r.db.Delete(&domain.SceneSnapshot{}, id).Error
+}
+
+// Restore はスナップショットのエンティティを作り直す
+// replaceなら現在のエンティティを置き換え、そうでなければ追加する（ゴミ箱からの取り消し用）
+// 削除済み・却下済み・取り下げ中のアートワークのエンティティは復元できないので飛ばす
+func (r *SceneSnapshotRepository) Restore(snapshot *domain.SceneSnapshot, replace bool) (*SnapshotRestoreResult, error) {
+	var saved []domain.SnapshotEntity
+	if snapshot.Entities != nil {
+		if err := json.Unmarshal(*snapshot.Entities, &saved); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &SnapshotRestoreResult{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// ゴミ箱は一度だけ取り消せる（同時に取り消されても二重に復元しない）
+		if snapshot.Kind == domain.SnapshotKindTrash {
+			deleted := tx.Delete(&domain.SceneSnapshot{}, snapshot.ID)
+			if deleted.Error != nil {
+				return deleted.Error
+			}
+			if deleted.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		artworkIDs := make([]uint, 0, len(saved))
+		for _, e := range saved {
+			artworkIDs = append(artworkIDs, e.ArtworkID)
+		}
+		// 保存後に承認が取り消された・取り下げられた作品を壁に戻さない
+		var existing []uint
+		if len(artworkIDs) > 0 {
+			if err := tx.Model(&domain.Artwork{}).Where("id IN ? AND status = ? AND taken_down_at IS NULL", artworkIDs, domain.ArtworkStatusApproved).Pluck("id", &existing).Error; err != nil {
+				return err
+			}
+		}
+		exists := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			exists[id] = true
+		}
+
+		if replace {
+			if err := tx.Where("scene_id = ?", snapshot.SceneID).Delete(&domain.SceneEntity{}).Error; err != nil {
+				return err
+			}
+			// 置き換える前の状態のrotateのキューは残さない
+			if err := tx.Where("scene_id = ?", snapshot.SceneID).Delete(&domain.QueuedEntity{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, e := range saved {
+			if !exists[e.ArtworkID] {
+				result.Skipped++
+				continue
+			}
+			entity := domain.SceneEntity{
+				SceneID:         snapshot.SceneID,
+				ArtworkID:       e.ArtworkID,
+				InitX:           e.InitX,
+				InitY:           e.InitY,
+				InitVX:          e.InitVX,
+				InitVY:          e.InitVY,
+				InitAngle:       e.InitAngle,
+				InitScale:       e.InitScale,
+				AnimationKind:   e.AnimationKind,
+				AnimationParams: e.AnimationParams,
+				RNGSeed:         e.RNGSeed,
+			}
+			if err := tx.Omit(clause.Associations).Create(&entity).Error; err != nil {
+				return err
+			}
+			result.Entities = append(result.Entities, entity)
+
+			if e.State != nil {
+				state := *e.State
+				state.EntityID = entity.ID
+				state.SceneID = snapshot.SceneID
+				result.States = append(result.States, &state)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TakedownRepository struct {
+	db *gorm.DB
+}
+
+func NewTakedownRepository(db *gorm.DB) *TakedownRepository {
+	return &TakedownRepository{db: db}
+}
+
+// Takedown はアートワークを取り下げ、全シーンのエンティティとrotateのキューから外して記録を残す
+// 削除したエンティティを返す（表示中のディスプレイへの通知用）
+// 既に取り下げ中ならgorm.ErrRecordNotFoundを返す
+func (r *TakedownRepository) Takedown(artworkID uint, reason *string, performedBy *uint) (*domain.ArtworkTakedown, []domain.SceneEntity, error) {
+	var record *domain.ArtworkTakedown
+	var entities []domain.SceneEntity
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		updated := tx.Model(&domain.Artwork{}).
+			Where("id = ? AND taken_down_at IS NULL", artworkID).
+			Update("taken_down_at", time.Now())
+		if updated.Error != nil {
+			return updated.Error
+		}
+		if updated.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("artwork_id = ?", artworkID).Order("id").Find(&entities).Error; err != nil {
+			return err
+		}
+		var queuedSceneIDs []uint
+		if err := tx.Model(&domain.QueuedEntity{}).Where("artwork_id = ?", artworkID).Pluck("scene_id", &queuedSceneIDs).Error; err != nil {
+			return err
+		}
+
+		sceneIDs := make([]uint, 0, len(entities)+len(queuedSceneIDs))
+		for _, entity := range entities {
+			sceneIDs = appendUnique(sceneIDs, entity.SceneID)
+		}
+		for _, sceneID := range queuedSceneIDs {
+			sceneIDs = appendUnique(sceneIDs, sceneID)
+		}
+
+		if err := tx.Where("artwork_id = ?", artworkID).Delete(&domain.SceneEntity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("artwork_id = ?", artworkID).Delete(&domain.QueuedEntity{}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		record, err = createTakedownRecord(tx, artworkID, domain.TakedownActionTakedown, reason, performedBy, sceneIDs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, entities, nil
+}
+
+// Reinstate は取り下げを解除し、取り下げ時に表示されていたシーンのうち今も存在するものを返す
+// 取り下げ中でなければgorm.ErrRecordNotFoundを返す
+func (r *TakedownRepository) Reinstate(artworkID uint, reason *string, performedBy *uint) (*domain.ArtworkTakedown, []uint, error) {
+	var record *domain.ArtworkTakedown
+	var sceneIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		updated := tx.Model(&domain.Artwork{}).
+			Where("id = ? AND taken_down_at IS NOT NULL", artworkID).
+			Update("taken_down_at", nil)
+		if updated.Error != nil {
+			return updated.Error
+		}
+		if updated.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var last domain.ArtworkTakedown
+		err := tx.Where("artwork_id = ? AND action = ?", artworkID, domain.TakedownActionTakedown).
+			Order("created_at DESC, id DESC").
+			First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var previous []uint
+		if last.SceneIDs != nil {
+			if err := json.Unmarshal(*last.SceneIDs, &previous); err != nil {
+				return err
+			}
+		}
+		if len(previous) > 0 {
+			// 取り下げ中に削除されたシーンには戻さない
+			if err := tx.Model(&domain.Scene{}).Where("id IN ?", previous).Order("id").Pluck("id", &sceneIDs).Error; err != nil {
+				return err
+			}
+		}
+
+		record, err = createTakedownRecord(tx, artworkID, domain.TakedownActionReinstate, reason, performedBy, sceneIDs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, sceneIDs, nil
+}
+
+func createTakedownRecord(tx *gorm.DB, artworkID uint, action string, reason *string, performedBy *uint, sceneIDs []uint) (*domain.ArtworkTakedown, error) {
+	if sceneIDs == nil {
+		sceneIDs = []uint{}
+	}
+	data, err := json.Marshal(sceneIDs)
+	if err != nil {
+		return nil, err
+	}
+	sceneIDsJSON := json.RawMessage(data)
+
+	record := &domain.ArtworkTakedown{
+		ArtworkID:   artworkID,
+		Action:      action,
+		Reason:      reason,
+		PerformedBy: performedBy,
+		SceneIDs:    &sceneIDsJSON,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ListTakenDown は取り下げ中のアートワークを記録付きで新しい順に返す
+func (r *TakedownRepository) ListTakenDown() ([]domain.Artwork, error) {
+	var artworks []domain.Artwork
+	err := r.db.Preload("Asset").
+		Preload("Takedowns", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at, id")
+		}).
+		Where("taken_down_at IS NOT NULL").
+		Order("taken_down_at DESC").
+		Find(&artworks).Error
+	return artworks, err
+}
+
+// History はアートワークの取り下げ・復帰の記録を古い順に返す（アートワークが削除済みでも残る）
+func (r *TakedownRepository) History(artworkID uint) ([]domain.ArtworkTakedown, error) {
+	var records []domain.ArtworkTakedown
+	err := r.db.Where("artwork_id = ?", artworkID).Order("created_at, id").Find(&records).Error
+	return records, err
+}
+
+func appendUnique(ids []uint, id uint) []uint {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
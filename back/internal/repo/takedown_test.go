@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestAppendUnique(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []uint
+		id   uint
+		want []uint
+	}{
+		{name: "空に追加", ids: nil, id: 1, want: []uint{1}},
+		{name: "末尾に追加", ids: []uint{1, 2}, id: 3, want: []uint{1, 2, 3}},
+		{name: "重複は追加しない", ids: []uint{1, 2}, id: 1, want: []uint{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendUnique(tt.ids, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("appendUnique(%v, %d) = %v, want %v", tt.ids, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func recordSceneIDs(t *testing.T, record *domain.ArtworkTakedown) []uint {
+	t.Helper()
+	var ids []uint
+	if err := json.Unmarshal(*record.SceneIDs, &ids); err != nil {
+		t.Fatalf("invalid scene_ids: %v", err)
+	}
+	return ids
+}
+
+func TestTakedownAndReinstate(t *testing.T) {
+	tests := []struct {
+		name string
+		// 表示中のシーン数と、rotateのキューにだけ入っているシーン数
+		displayed int
+		queued    int
+		// 取り下げ中に削除するシーン（displayed+queuedの添字）
+		deleted []int
+	}{
+		{name: "表示中のシーンに戻す", displayed: 2},
+		{name: "キューに退避中のシーンも記録する", displayed: 1, queued: 1},
+		{name: "削除されたシーンには戻さない", displayed: 2, deleted: []int{0}},
+		{name: "どこにも表示されていない", displayed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			takedowns := NewTakedownRepository(db)
+			artwork := createTestArtwork(t, db, 0)
+			other := createTestArtwork(t, db, 0)
+
+			var sceneIDs []uint
+			for i := 0; i < tt.displayed+tt.queued; i++ {
+				scene := createTestScene(t, db, domain.Scene{})
+				sceneIDs = append(sceneIDs, scene.ID)
+				// 他のアートワークのエンティティは残る
+				createTestEntity(t, db, scene.ID, other.ID, "pulsate")
+				if i < tt.displayed {
+					createTestEntity(t, db, scene.ID, artwork.ID, "pulsate")
+					continue
+				}
+				queued := domain.QueuedEntity{SceneID: scene.ID, ArtworkID: artwork.ID, InitScale: 0.25, AnimationKind: "pulsate", RNGSeed: 1, QueuedAt: time.Now()}
+				if err := db.Create(&queued).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reason := "苦情"
+			record, removed, err := takedowns.Takedown(artwork.ID, &reason, nil)
+			if err != nil {
+				t.Fatalf("Takedown() error = %v", err)
+			}
+			if len(removed) != tt.displayed {
+				t.Errorf("removed %d entities, want %d", len(removed), tt.displayed)
+			}
+			if got := recordSceneIDs(t, record); !sameIDs(got, sceneIDs) {
+				t.Errorf("takedown scene_ids = %v, want %v", got, sceneIDs)
+			}
+
+			var entities, queued, others int64
+			db.Model(&domain.SceneEntity{}).Where("artwork_id = ?", artwork.ID).Count(&entities)
+			db.Model(&domain.QueuedEntity{}).Where("artwork_id = ?", artwork.ID).Count(&queued)
+			db.Model(&domain.SceneEntity{}).Where("artwork_id = ?", other.ID).Count(&others)
+			if entities != 0 || queued != 0 {
+				t.Errorf("entities = %d, queued = %d after takedown, want 0", entities, queued)
+			}
+			if int(others) != len(sceneIDs) {
+				t.Errorf("other artwork entities = %d, want %d", others, len(sceneIDs))
+			}
+
+			// 二重の取り下げはできない
+			if _, _, err := takedowns.Takedown(artwork.ID, nil, nil); !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Errorf("second Takedown() error = %v, want ErrRecordNotFound", err)
+			}
+
+			deleted := make(map[uint]bool)
+			for _, i := range tt.deleted {
+				if err := db.Where("scene_id = ?", sceneIDs[i]).Delete(&domain.SceneEntity{}).Error; err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Delete(&domain.Scene{}, sceneIDs[i]).Error; err != nil {
+					t.Fatal(err)
+				}
+				deleted[sceneIDs[i]] = true
+			}
+			var want []uint
+			for _, id := range sceneIDs {
+				if !deleted[id] {
+					want = append(want, id)
+				}
+			}
+
+			record, restored, err := takedowns.Reinstate(artwork.ID, nil, nil)
+			if err != nil {
+				t.Fatalf("Reinstate() error = %v", err)
+			}
+			if !sameIDs(restored, want) {
+				t.Errorf("reinstated scenes = %v, want %v", restored, want)
+			}
+			if got := recordSceneIDs(t, record); !sameIDs(got, want) {
+				t.Errorf("reinstate scene_ids = %v, want %v", got, want)
+			}
+
+			// 取り下げ中でなければ復帰できない
+			if _, _, err := takedowns.Reinstate(artwork.ID, nil, nil); !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Errorf("second Reinstate() error = %v, want ErrRecordNotFound", err)
+			}
+
+			history, err := takedowns.History(artwork.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actions []string
+			for _, h := range history {
+				actions = append(actions, h.Action)
+			}
+			if want := []string{domain.TakedownActionTakedown, domain.TakedownActionReinstate}; !reflect.DeepEqual(actions, want) {
+				t.Errorf("history = %v, want %v", actions, want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"image"
@@ -8,23 +9,51 @@ import (
 	"image/png"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
 )
 
+// UploadLimits はアップロードを受け付ける画像の上限
+type UploadLimits struct {
+	MaxBytes int64
+	// デコード後のピクセル数（幅×高さ）の上限。デコード前にヘッダーだけ読んで確認する
+	MaxPixels int64
+	// 中身から判定したMIMEタイプで許可するもの
+	AllowedMimes []string
+}
+
+// アップロードを拒否した理由（クライアントが表示を出し分けるためのコード）
+const (
+	UploadErrorFileTooLarge    = "file_too_large"
+	UploadErrorImageTooLarge   = "image_too_large"
+	UploadErrorUnsupportedType = "unsupported_type"
+	UploadErrorInvalidImage    = "invalid_image"
+)
+
+// UploadError はアップロードされた画像が受け付けられないときのエラー
+type UploadError struct {
+	Code    string
+	Message string
+}
+
+func (e *UploadError) Error() string {
+	return e.Message
+}
+
 type ImageProcessor struct {
 	AssetDir string
+	Limits   UploadLimits
 }
 
-func NewImageProcessor(assetDir string) *ImageProcessor {
+func NewImageProcessor(assetDir string, limits UploadLimits) *ImageProcessor {
 	// アセットディレクトリを作成
 	os.MkdirAll(assetDir, 0755)
-	return &ImageProcessor{AssetDir: assetDir}
+	return &ImageProcessor{AssetDir: assetDir, Limits: limits}
 }
 
 type ProcessedImage struct {
@@ -92,16 +121,15 @@ func checkImageHasTransparency(img image.Image) bool {
 }
 
 func (ip *ImageProcessor) ProcessUpload(file multipart.File, header *multipart.FileHeader) (*ProcessedImage, error) {
-	// ファイルを読み込み
-	data, err := io.ReadAll(file)
+	data, err := ip.readUpload(file, header)
 	if err != nil {
 		return nil, err
 	}
 
 	// 画像をデコード
-	img, _, err := image.Decode(strings.NewReader(string(data)))
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %v", err)
+		return nil, &UploadError{Code: UploadErrorInvalidImage, Message: fmt.Sprintf("failed to decode image: %v", err)}
 	}
 
 	// 元のサイズを取得
@@ -218,6 +246,69 @@ func (ip *ImageProcessor) ProcessUpload(file multipart.File, header *multipart.F
 	}, nil
 }
 
+// readUpload はファイルを上限まで読み込み、形式とピクセル数をデコード前に検証する
+// 小さなファイルでも巨大な画像に展開されることがあるので、サイズだけでは防げない
+func (ip *ImageProcessor) readUpload(file multipart.File, header *multipart.FileHeader) ([]byte, error) {
+	tooLarge := &UploadError{
+		Code:    UploadErrorFileTooLarge,
+		Message: fmt.Sprintf("file exceeds %d bytes", ip.Limits.MaxBytes),
+	}
+	if ip.Limits.MaxBytes > 0 && header.Size > ip.Limits.MaxBytes {
+		return nil, tooLarge
+	}
+
+	// ヘッダーのサイズは信用せず、上限+1バイトまでしか読まない
+	var reader io.Reader = file
+	if ip.Limits.MaxBytes > 0 {
+		reader = io.LimitReader(file, ip.Limits.MaxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if ip.Limits.MaxBytes > 0 && int64(len(data)) > ip.Limits.MaxBytes {
+		return nil, tooLarge
+	}
+
+	// Content-Typeや拡張子ではなく中身で判定する
+	mime := http.DetectContentType(data)
+	if !ip.mimeAllowed(mime) {
+		return nil, &UploadError{
+			Code:    UploadErrorUnsupportedType,
+			Message: fmt.Sprintf("unsupported image type: %s", mime),
+		}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &UploadError{Code: UploadErrorInvalidImage, Message: fmt.Sprintf("failed to decode image: %v", err)}
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, &UploadError{Code: UploadErrorInvalidImage, Message: "image has no pixels"}
+	}
+	if ip.Limits.MaxPixels > 0 && int64(config.Width)*int64(config.Height) > ip.Limits.MaxPixels {
+		return nil, &UploadError{
+			Code:    UploadErrorImageTooLarge,
+			Message: fmt.Sprintf("image is %dx%d, exceeds %d pixels", config.Width, config.Height, ip.Limits.MaxPixels),
+		}
+	}
+
+	return data, nil
+}
+
+func (ip *ImageProcessor) mimeAllowed(mime string) bool {
+	// 空なら制限しない（デコードできる形式のみ通る）
+	if len(ip.Limits.AllowedMimes) == 0 {
+		return true
+	}
+	for _, allowed := range ip.Limits.AllowedMimes {
+		if allowed == mime {
+			return true
+		}
+	}
+	return false
+}
+
 func (ip *ImageProcessor) saveImage(img image.Image, filePath, mime string) error {
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -0,0 +1,117 @@
+package ws
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"log"
+)
+
+// 全クライアント宛て・インスタンス間制御用の特別なルーム名
+const (
+	allRoom     = "all"
+	controlRoom = "control"
+)
+
+// Broadcaster はルーム宛てのメッセージを全バックエンドインスタンスへ届ける配信バックエンド
+// Subscribeは受信したメッセージをdeliverに渡し続ける（ブロックしてよい）
+type Broadcaster interface {
+	Publish(room string, payload []byte) error
+	Subscribe(deliver func(room string, payload []byte))
+}
+
+// SceneEventPublisher はシーンのリビジョンの採番と配信を1つの操作で行えるBroadcaster
+// 採番から配信までの間に他のインスタンスが割り込まないので、複数インスタンスから配信しても
+// 全インスタンスにrev順に届く。messageはrevを含まないMessageのJSONで、revは採番した値が入る
+type SceneEventPublisher interface {
+	PublishSceneEvent(room string, sceneID uint, message []byte) (int64, error)
+}
+
+// roomEvent はBroadcasterを流れるメッセージ
+type roomEvent struct {
+	SceneID uint            `json:"scene_id,omitempty"`
+	Seq     int64           `json:"seq,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Control *controlEvent   `json:"control,omitempty"`
+}
+
+// controlEvent は各インスタンスが自分に接続中のクライアントに対して行う操作
+type controlEvent struct {
+	Action    string              `json:"action"`
+	APIKeyID  uint                `json:"api_key_id,omitempty"`
+	DeviceKey string              `json:"device_key,omitempty"`
+	Node      *domain.DisplayNode `json:"node,omitempty"`
+	SceneID   uint                `json:"scene_id,omitempty"`
+}
+
+const (
+	controlDisconnectAPIKey = "disconnect_api_key"
+	controlDisconnectDevice = "disconnect_device"
+	controlDisplayConfig    = "display_config"
+	controlForgetScene      = "forget_scene"
+)
+
+// publish はBroadcasterが設定されていればそちらへ、なければ直接ローカルに配信する
+func (h *Hub) publish(room string, event roomEvent) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling room event: %v", err)
+		return
+	}
+
+	if h.broadcaster == nil {
+		h.deliver(room, payload)
+		return
+	}
+
+	if err := h.broadcaster.Publish(room, payload); err != nil {
+		// Redisが落ちていても自インスタンスのクライアントには届ける
+		log.Printf("Failed to publish to %s, delivering locally: %v", room, err)
+		h.deliver(room, payload)
+	}
+}
+
+// deliver はBroadcasterから受け取ったメッセージを自インスタンスのクライアントへ配る
+func (h *Hub) deliver(room string, payload []byte) {
+	var event roomEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		log.Printf("Invalid room event on %s: %v", room, err)
+		return
+	}
+
+	switch {
+	case event.Control != nil:
+		h.handleControl(event.Control)
+	case room == allRoom:
+		h.broadcastDataToAll(event.Data)
+	case event.SceneID != 0 && event.Seq > 0:
+		// 各インスタンスが同じイベントログを持つので、どのインスタンスに再接続しても差分送信できる
+		h.eventMu.Lock()
+		events, ok := h.eventLogs[event.SceneID]
+		if !ok {
+			events = &eventLog{}
+			h.eventLogs[event.SceneID] = events
+		}
+		events.append(event.Seq, event.Data)
+		h.broadcastDataToRoom(room, event.Data)
+		h.eventMu.Unlock()
+	default:
+		h.broadcastDataToRoom(room, event.Data)
+	}
+}
+
+func (h *Hub) handleControl(control *controlEvent) {
+	switch control.Action {
+	case controlDisconnectAPIKey:
+		h.disconnectAPIKeyLocal(control.APIKeyID)
+	case controlDisconnectDevice:
+		h.disconnectDeviceKeyLocal(control.DeviceKey)
+	case controlDisplayConfig:
+		if control.Node != nil {
+			h.applyDisplayConfigLocal(control.Node)
+		}
+	case controlForgetScene:
+		h.forgetSceneLocal(control.SceneID)
+	default:
+		log.Printf("Unknown control action: %s", control.Action)
+	}
+}
@@ -0,0 +1,44 @@
+package ws
+
+import "log"
+
+// EnforceCapacity はシーンのエンティティ数を定員に合わせ、退場したエンティティにentity.remove、
+// rotateのキューから戻したエンティティにentity.addを配信する
+// keepには追加したばかりで退場させたくないエンティティを渡す
+func (h *Hub) EnforceCapacity(sceneID uint, keep ...uint) {
+	changes, err := h.capacityRepo.Enforce(sceneID, keep)
+	if err != nil {
+		log.Printf("Failed to enforce scene capacity: scene_id=%d, error=%v", sceneID, err)
+		return
+	}
+
+	reconcile := false
+	for i := range changes.Evicted {
+		entity := &changes.Evicted[i]
+		if err := h.stateRepo.Delete(entity.SceneID, entity.ID); err != nil {
+			log.Printf("Failed to clear entity state: entity_id=%d, error=%v", entity.ID, err)
+		}
+		h.PublishSceneEvent(sceneID, Message{
+			Type: "entity.remove",
+			Data: map[string]interface{}{
+				"entity_id":  entity.ID,
+				"artwork_id": entity.ArtworkID,
+				"reason":     RemoveReasonEvicted,
+			},
+		})
+		reconcile = reconcile || entity.AnimationKind == "spin_fight"
+	}
+	for i := range changes.Restored {
+		entity := &changes.Restored[i]
+		h.PublishSceneEvent(sceneID, Message{
+			Type: "entity.add",
+			Data: EntityPayload(entity),
+		})
+		reconcile = reconcile || entity.AnimationKind == "spin_fight"
+	}
+
+	// 対戦相手が退場した・キューから戻った場合は組み直す
+	if reconcile {
+		h.ReconcileFights(sceneID)
+	}
+}
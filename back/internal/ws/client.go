@@ -1,8 +1,8 @@
 package ws
 
 import (
+	"culture-festival-backend/internal/domain"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -31,6 +31,8 @@ type Client struct {
 	send      chan []byte
 	room      string
 	deviceKey string
+	apiKeyID  uint
+	node      *domain.DisplayNode
 }
 
 type ClientMessage struct {
@@ -42,6 +44,7 @@ type ClientMessage struct {
 type DisplayHelloData struct {
 	DisplayKey string `json:"display_key"`
 	SceneID    uint   `json:"scene_id"`
+	LastSeq    int64  `json:"last_seq"`
 	Caps       struct {
 		W       int     `json:"w"`
 		H       int     `json:"h"`
@@ -134,22 +137,31 @@ func (c *Client) handleMessage(msg ClientMessage) {
 		var data DisplayHelloData
 		if jsonData, err := json.Marshal(msg.Data); err == nil {
 			json.Unmarshal(jsonData, &data)
-			c.deviceKey = data.DisplayKey
-			room := fmt.Sprintf("scene:%d", data.SceneID)
-			c.hub.MoveClientToRoom(c, room)
-			log.Printf("Display node connected: %s to scene %d", data.DisplayKey, data.SceneID)
+			c.hub.handleDisplayHello(c, data)
+		}
+	case "clock.ping":
+		var data ClockPingData
+		if jsonData, err := json.Marshal(msg.Data); err == nil {
+			json.Unmarshal(jsonData, &data)
+			c.hub.handleClockPing(c, data)
 		}
 	case "state.report":
-		// 状態報告は現在は無視（将来の同期機能で使用）
 		var data StateReportData
 		if jsonData, err := json.Marshal(msg.Data); err == nil {
 			json.Unmarshal(jsonData, &data)
-			log.Printf("State report from %s: entity %d at (%.2f, %.2f)", c.deviceKey, data.EntityID, data.X, data.Y)
+			c.hub.handleStateReport(c, data)
 		}
 	}
 }
 
-func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
+// closeWithReason はクローズフレームを送ってから接続を切断する
+func (c *Client) closeWithReason(code int, reason string) {
+	deadline := time.Now().Add(writeWait)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	c.conn.Close()
+}
+
+func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request, apiKeyID uint) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -157,10 +169,11 @@ func ServeWS(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
-		room: "default",
+		hub:      hub,
+		conn:     conn,
+		send:     make(chan []byte, 256),
+		room:     "default",
+		apiKeyID: apiKeyID,
 	}
 
 	client.hub.register <- client
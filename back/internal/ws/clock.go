@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// クライアントの物理演算tick（約60FPS）
+	tickMs = 1000.0 / 60.0
+	// clock.syncを各ルームへ再送する間隔
+	clockSyncPeriod = 10 * time.Second
+)
+
+type ClockPingData struct {
+	ClientTS int64 `json:"client_ts"`
+}
+
+// sceneEpoch はルームの基準時刻を返す。未設定なら現在時刻で初期化する
+// 全インスタンスで同じ値になるようRedisに置き、Redisに接続できないときだけプロセス内の値を使う
+func (h *Hub) sceneEpoch(room string) time.Time {
+	epoch, err := h.clockRepo.Epoch(room)
+	if err == nil {
+		return epoch
+	}
+	log.Printf("Failed to get scene epoch from Redis, using local epoch: room=%s, error=%v", room, err)
+
+	h.clockMu.Lock()
+	defer h.clockMu.Unlock()
+
+	epoch, ok := h.epochs[room]
+	if !ok {
+		epoch = time.Now()
+		h.epochs[room] = epoch
+	}
+	return epoch
+}
+
+// ResetClock はシーンの基準時刻を現在時刻に戻し、ルームへclock.syncを送る
+// 基準時刻はRedisで共有しているので、clock.syncは全インスタンスのクライアントへ送る
+func (h *Hub) ResetClock(room string) {
+	if _, err := h.clockRepo.Reset(room); err != nil {
+		log.Printf("Failed to reset scene epoch in Redis, resetting local epoch: room=%s, error=%v", room, err)
+		h.clockMu.Lock()
+		h.epochs[room] = time.Now()
+		h.clockMu.Unlock()
+	}
+
+	h.BroadcastToRoom(room, h.clockSyncMessage(room))
+}
+
+func (h *Hub) clockSyncMessage(room string) Message {
+	return Message{
+		Type: "clock.sync",
+		Data: map[string]interface{}{
+			"t0":          h.sceneEpoch(room).UnixMilli(),
+			"server_time": time.Now().UnixMilli(),
+			"tick_ms":     tickMs,
+		},
+	}
+}
+
+// broadcastClockSync は自インスタンスに接続中の全シーンルームのクライアントへclock.syncを送る
+// 各インスタンスが自分のクライアントにだけ送る（Broadcasterを通すと他のインスタンスの分と重複する）
+func (h *Hub) broadcastClockSync() {
+	h.mu.RLock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		if strings.HasPrefix(room, "scene:") {
+			rooms = append(rooms, room)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		data, err := json.Marshal(h.clockSyncMessage(room))
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
+			continue
+		}
+		h.broadcastDataToRoom(room, data)
+	}
+}
+
+// handleClockPing はNTP方式でオフセットと遅延を推定できるよう受信・送信時刻を返す
+func (h *Hub) handleClockPing(client *Client, data ClockPingData) {
+	receivedAt := time.Now().UnixMilli()
+
+	h.mu.RLock()
+	room := client.room
+	h.mu.RUnlock()
+
+	h.SendToClient(client, Message{
+		Type: "clock.pong",
+		Data: map[string]interface{}{
+			"client_ts":      data.ClientTS,
+			"server_recv_ts": receivedAt,
+			"server_send_ts": time.Now().UnixMilli(),
+			"t0":             h.sceneEpoch(room).UnixMilli(),
+		},
+	})
+}
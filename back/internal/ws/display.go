@@ -0,0 +1,154 @@
+package ws
+
+import (
+	"culture-festival-backend/internal/domain"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// シーンから切り離されたディスプレイの待機ルーム
+const unassignedRoom = "unassigned"
+
+// SceneRoom はシーンIDに対応するルーム名を返す
+func SceneRoom(sceneID uint) string {
+	return fmt.Sprintf("scene:%d", sceneID)
+}
+
+// DisplayConfigMessage はDisplayNodeのviewportを通知するdisplay.configメッセージを作る
+func DisplayConfigMessage(node *domain.DisplayNode) Message {
+	return Message{
+		Type: "display.config",
+		Data: map[string]interface{}{
+			"display_id": node.ID,
+			"name":       node.Name,
+			"scene_id":   node.SceneID,
+			"viewport": map[string]interface{}{
+				"x":      node.ViewportX,
+				"y":      node.ViewportY,
+				"width":  node.ViewportW,
+				"height": node.ViewportH,
+				"scale":  node.Scale,
+			},
+			"pixel": map[string]interface{}{
+				"width":  node.PixelWidth,
+				"height": node.PixelHeight,
+			},
+		},
+	}
+}
+
+// handleDisplayHello は登録済みDisplayNodeのdevice_keyで認証し、ノードのシーンに参加させる
+func (h *Hub) handleDisplayHello(client *Client, data DisplayHelloData) {
+	node, err := h.displayRepo.GetByDeviceKey(data.DisplayKey)
+	if err != nil {
+		log.Printf("Unknown display key rejected: %s", data.DisplayKey)
+		client.closeWithReason(websocket.ClosePolicyViolation, "unknown display key")
+		return
+	}
+
+	h.setClientNode(client, node)
+
+	if node.SceneID == nil {
+		// シーンが割り当てられるまでdisplay.config（scene_id: null）だけ送って待機させる
+		h.MoveClientToRoom(client, unassignedRoom)
+		h.SendToClient(client, DisplayConfigMessage(node))
+		log.Printf("Display node connected without scene: %s (%s)", node.Name, node.DeviceKey)
+		return
+	}
+	sceneID := *node.SceneID
+
+	// クライアントが申告したscene_idは無視し、登録済みのシーンに参加させる
+	if data.SceneID != 0 && data.SceneID != sceneID {
+		log.Printf("Display %s claimed scene %d, using registered scene %d", node.Name, data.SceneID, sceneID)
+	}
+
+	room := SceneRoom(sceneID)
+	h.SendToClient(client, DisplayConfigMessage(node))
+	h.SendToClient(client, h.clockSyncMessage(room))
+
+	// ルーム参加と差分送信の間に新しいイベントが割り込まないようeventMuを保持する
+	h.eventMu.Lock()
+	h.MoveClientToRoom(client, room)
+	replayed := data.SceneID == sceneID && h.replayMissedEvents(client, sceneID, data.LastSeq)
+	h.eventMu.Unlock()
+
+	if !replayed {
+		h.sendSceneSnapshot(client, node.Scene)
+	}
+
+	log.Printf("Display node connected: %s (%s) to scene %d", node.Name, node.DeviceKey, sceneID)
+}
+
+// GetClientsByDeviceKey は指定したdevice_keyで接続中のクライアントを返す
+func (h *Hub) GetClientsByDeviceKey(deviceKey string) []*Client {
+	deviceKey = strings.TrimSpace(deviceKey)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var clients []*Client
+	for client := range h.clients {
+		if client.deviceKey == deviceKey {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// ApplyDisplayConfig は接続中のディスプレイを再接続なしでノードのシーンへ移し、
+// 新しいdisplay.configを送る（全インスタンスに反映）
+// 更新したクライアント数を返す。Broadcaster経由で他のインスタンスにも送った場合は全体の数が分からないのでnil
+func (h *Hub) ApplyDisplayConfig(node *domain.DisplayNode) *int {
+	if h.broadcaster == nil {
+		updated := h.applyDisplayConfigLocal(node)
+		return &updated
+	}
+	h.publish(controlRoom, roomEvent{Control: &controlEvent{
+		Action: controlDisplayConfig,
+		Node:   node,
+	}})
+	return nil
+}
+
+func (h *Hub) applyDisplayConfigLocal(node *domain.DisplayNode) int {
+	clients := h.GetClientsByDeviceKey(node.DeviceKey)
+	room := unassignedRoom
+	if node.SceneID != nil {
+		room = SceneRoom(*node.SceneID)
+	}
+	message := DisplayConfigMessage(node)
+
+	for _, client := range clients {
+		h.setClientNode(client, node)
+		h.MoveClientToRoom(client, room)
+		h.SendToClient(client, message)
+		if node.SceneID == nil {
+			continue
+		}
+		h.SendToClient(client, h.clockSyncMessage(room))
+		h.sendSceneSnapshot(client, node.Scene)
+	}
+
+	if len(clients) > 0 {
+		log.Printf("Display config pushed: %s to %d client(s) in %s", node.Name, len(clients), room)
+	}
+	return len(clients)
+}
+
+// DisconnectDeviceKey は削除されたディスプレイの接続を全インスタンスで切断する
+func (h *Hub) DisconnectDeviceKey(deviceKey string) {
+	h.publish(controlRoom, roomEvent{Control: &controlEvent{
+		Action:    controlDisconnectDevice,
+		DeviceKey: deviceKey,
+	}})
+}
+
+func (h *Hub) disconnectDeviceKeyLocal(deviceKey string) {
+	clients := h.GetClientsByDeviceKey(deviceKey)
+	for _, client := range clients {
+		client.closeWithReason(websocket.ClosePolicyViolation, "display removed")
+	}
+}
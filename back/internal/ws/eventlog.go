@@ -0,0 +1,85 @@
+package ws
+
+// ルームごとに保持するイベント数。これより多く取りこぼした場合はscene.snapshotで再同期する
+const eventLogSize = 256
+
+type loggedEvent struct {
+	seq  int64
+	data []byte
+}
+
+// eventLog はシーンイベントを直近eventLogSize件だけ保持するリングバッファ
+type eventLog struct {
+	events [eventLogSize]loggedEvent
+	head   int
+	count  int
+}
+
+func (l *eventLog) append(seq int64, data []byte) {
+	idx := (l.head + l.count) % eventLogSize
+	l.events[idx] = loggedEvent{seq: seq, data: data}
+	if l.count < eventLogSize {
+		l.count++
+	} else {
+		l.head = (l.head + 1) % eventLogSize
+	}
+}
+
+func (l *eventLog) at(i int) loggedEvent {
+	return l.events[(l.head+i)%eventLogSize]
+}
+
+// since はlastSeqより後のイベントを返す。バッファから欠けている場合はfalse
+func (l *eventLog) since(lastSeq int64) ([][]byte, bool) {
+	if l.count == 0 {
+		return nil, false
+	}
+
+	oldest := l.at(0).seq
+	newest := l.at(l.count - 1).seq
+	if lastSeq > newest || lastSeq < oldest-1 {
+		return nil, false
+	}
+
+	var missed [][]byte
+	expected := lastSeq + 1
+	for i := 0; i < l.count; i++ {
+		event := l.at(i)
+		if event.seq <= lastSeq {
+			continue
+		}
+		if event.seq != expected {
+			// 途中が欠けていれば差分では復元できない
+			return nil, false
+		}
+		missed = append(missed, event.data)
+		expected++
+	}
+	return missed, true
+}
+
+// replayMissedEvents はlast_seq以降の取りこぼしイベントをクライアントに送る
+// 差分で復元できない場合はfalseを返すので、呼び出し側でscene.snapshotを送る
+// eventMuを保持した状態で呼ぶこと
+func (h *Hub) replayMissedEvents(client *Client, sceneID uint, lastSeq int64) bool {
+	if lastSeq <= 0 {
+		return false
+	}
+
+	events, ok := h.eventLogs[sceneID]
+	if !ok {
+		// サーバ再起動などでバッファが空の場合、最新リビジョンと一致していれば取りこぼしなし
+		rev, err := h.revisionRepo.Get(sceneID)
+		return err == nil && rev == lastSeq
+	}
+
+	missed, ok := events.since(lastSeq)
+	if !ok {
+		return false
+	}
+
+	for _, data := range missed {
+		h.sendDataToClient(client, data)
+	}
+	return true
+}
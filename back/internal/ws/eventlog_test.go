@@ -0,0 +1,142 @@
+package ws
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// seqLog はfrom..toのseqを順に積んだイベントログを作る（dataはseqの文字列）
+func seqLog(from, to int64) *eventLog {
+	l := &eventLog{}
+	for seq := from; seq <= to; seq++ {
+		l.append(seq, []byte(fmt.Sprint(seq)))
+	}
+	return l
+}
+
+func seqRange(from, to int64) []string {
+	seqs := []string{}
+	for seq := from; seq <= to; seq++ {
+		seqs = append(seqs, fmt.Sprint(seq))
+	}
+	return seqs
+}
+
+func TestEventLogSince(t *testing.T) {
+	gapped := seqLog(1, 3)
+	gapped.append(5, []byte("5"))
+
+	// 一周してseq 1..44が上書きされたログ
+	const wrappedNewest = eventLogSize + 44
+
+	tests := []struct {
+		name    string
+		log     *eventLog
+		lastSeq int64
+		want    []string
+		wantOK  bool
+	}{
+		{name: "空のログは差分で復元できない", log: &eventLog{}, lastSeq: 1},
+		{name: "途中から", log: seqLog(1, 5), lastSeq: 2, want: seqRange(3, 5), wantOK: true},
+		{name: "最新まで受信済み", log: seqLog(1, 5), lastSeq: 5, want: []string{}, wantOK: true},
+		{name: "最古の直前から", log: seqLog(10, 12), lastSeq: 9, want: seqRange(10, 12), wantOK: true},
+		{name: "最古より前は欠けている", log: seqLog(10, 12), lastSeq: 8},
+		{name: "サーバより新しいseq", log: seqLog(1, 5), lastSeq: 6},
+		{name: "途中のseqが欠けている", log: gapped, lastSeq: 2},
+		{name: "欠けより後からなら復元できる", log: gapped, lastSeq: 4, want: []string{"5"}, wantOK: true},
+		{name: "一周して上書きされた分は復元できない", log: seqLog(1, wrappedNewest), lastSeq: 43},
+		{name: "一周後の最古の直前から", log: seqLog(1, wrappedNewest), lastSeq: 44, want: seqRange(45, wrappedNewest), wantOK: true},
+		{name: "一周後の途中から", log: seqLog(1, wrappedNewest), lastSeq: wrappedNewest - 3, want: seqRange(wrappedNewest-2, wrappedNewest), wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.log.since(tt.lastSeq)
+			if ok != tt.wantOK {
+				t.Fatalf("since(%d) ok = %v, want %v", tt.lastSeq, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			seqs := []string{}
+			for _, data := range got {
+				seqs = append(seqs, string(data))
+			}
+			if !reflect.DeepEqual(seqs, tt.want) {
+				t.Errorf("since(%d) = %v, want %v", tt.lastSeq, seqs, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayMissedEvents(t *testing.T) {
+	const sceneID = 1
+
+	tests := []struct {
+		name    string
+		lastSeq int64
+		want    []string
+		wantOK  bool
+	}{
+		{name: "last_seqなしはスナップショット", lastSeq: 0},
+		{name: "取りこぼしを順に送る", lastSeq: 7, want: seqRange(8, 10), wantOK: true},
+		{name: "取りこぼしなし", lastSeq: 10, want: []string{}, wantOK: true},
+		{name: "バッファから欠けていればスナップショット", lastSeq: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(nil, nil, nil, nil, nil, nil, nil, nil)
+			h.eventLogs[sceneID] = seqLog(5, 10)
+			client := &Client{hub: h, send: make(chan []byte, eventLogSize)}
+			h.clients[client] = true
+
+			ok := h.replayMissedEvents(client, sceneID, tt.lastSeq)
+			if ok != tt.wantOK {
+				t.Fatalf("replayMissedEvents(%d) = %v, want %v", tt.lastSeq, ok, tt.wantOK)
+			}
+
+			sent := []string{}
+			for len(client.send) > 0 {
+				sent = append(sent, string(<-client.send))
+			}
+			if !ok {
+				if len(sent) != 0 {
+					t.Errorf("sent %v, want nothing", sent)
+				}
+				return
+			}
+			if !reflect.DeepEqual(sent, tt.want) {
+				t.Errorf("sent %v, want %v", sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverAppendsSceneEvents(t *testing.T) {
+	h := NewHub(nil, nil, nil, nil, nil, nil, nil, nil)
+	for seq := int64(1); seq <= 3; seq++ {
+		h.deliver(SceneRoom(2), []byte(fmt.Sprintf(`{"scene_id":2,"seq":%d,"data":%d}`, seq, seq)))
+	}
+	// seqのないメッセージ（clock.syncなど）は記録しない
+	h.deliver(SceneRoom(2), []byte(`{"data":99}`))
+
+	got, ok := h.eventLogs[2].since(1)
+	if !ok {
+		t.Fatal("since(1) ok = false, want true")
+	}
+	seqs := []string{}
+	for _, data := range got {
+		seqs = append(seqs, string(data))
+	}
+	if want := seqRange(2, 3); !reflect.DeepEqual(seqs, want) {
+		t.Errorf("since(1) = %v, want %v", seqs, want)
+	}
+
+	// シーン削除後は古いseqで差分送信しない
+	h.forgetSceneLocal(2)
+	if _, exists := h.eventLogs[2]; exists {
+		t.Error("event log remains after forgetSceneLocal")
+	}
+}
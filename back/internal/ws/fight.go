@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"culture-festival-backend/internal/domain"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	// 1試合の長さ
+	fightDuration = 20 * time.Second
+	// 決着後、次の相手を探すまでの休憩
+	fightCooldown = 5 * time.Second
+	// 時間切れ判定と取りこぼしたペアリングを拾う間隔
+	fightReconcilePeriod = time.Second
+)
+
+// ReconcileFights はシーンのspin_fight対戦を組み直し、fight.end/fight.startを配信する
+// エンティティの追加・削除・種類変更の後に呼ぶ
+func (h *Hub) ReconcileFights(sceneID uint) {
+	states, err := h.stateRepo.GetBySceneID(sceneID)
+	if err != nil {
+		// Redisが使えなくても初期位置でペアを組める
+		log.Printf("Failed to load entity state for fight pairing: scene_id=%d, error=%v", sceneID, err)
+	}
+
+	position := func(entity *domain.SceneEntity) (float64, float64) {
+		if state, ok := states[entity.ID]; ok {
+			return state.X, state.Y
+		}
+		return entity.InitX, entity.InitY
+	}
+	// 勢いは報告された速さ×大きさ（衝突で速度を交換するので、押し勝った方が速く残る）
+	power := func(entity *domain.SceneEntity) (float64, bool) {
+		state, ok := states[entity.ID]
+		if !ok {
+			return 0, false
+		}
+		scale := state.Scale
+		if scale <= 0 {
+			scale = entity.InitScale
+		}
+		return math.Hypot(state.VX, state.VY) * scale, true
+	}
+
+	changes, err := h.fightRepo.Reconcile(sceneID, fightDuration, fightCooldown, position, power)
+	if err != nil {
+		log.Printf("Failed to reconcile fights: scene_id=%d, error=%v", sceneID, err)
+		return
+	}
+
+	for i := range changes.Ended {
+		match := &changes.Ended[i]
+		data := fightPayload(match)
+		data["winner"] = match.WinnerEntityID
+		data["winner_artwork_id"] = match.WinnerArtworkID
+		data["reason"] = match.EndReason
+		h.PublishSceneEvent(sceneID, Message{Type: "fight.end", Data: data})
+	}
+	for i := range changes.Started {
+		h.PublishSceneEvent(sceneID, Message{Type: "fight.start", Data: fightPayload(&changes.Started[i])})
+	}
+}
+
+// runFightTicker は定期的に全シーンの対戦を進める
+func (h *Hub) runFightTicker() {
+	ticker := time.NewTicker(fightReconcilePeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sceneIDs, err := h.fightRepo.SceneIDsToReconcile()
+		if err != nil {
+			log.Printf("Failed to list scenes for fight pairing: %v", err)
+			continue
+		}
+		for _, sceneID := range sceneIDs {
+			h.ReconcileFights(sceneID)
+		}
+	}
+}
+
+func fightPayload(match *domain.FightMatch) map[string]interface{} {
+	return map[string]interface{}{
+		"match_id":   match.ID,
+		"a":          match.EntityAID,
+		"b":          match.EntityBID,
+		"started_at": match.StartedAt.UnixMilli(),
+		"ends_at":    match.EndsAt.UnixMilli(),
+	}
+}
@@ -1,9 +1,13 @@
 package ws
 
 import (
+	"culture-festival-backend/internal/repo"
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 type Hub struct {
@@ -13,24 +17,72 @@ type Hub struct {
 	unregister chan *Client
 	broadcast  chan []byte
 	mu         sync.RWMutex
+
+	// 複数インスタンス構成時の配信バックエンド（nilなら単一インスタンス）
+	broadcaster Broadcaster
+
+	// シーンごとの直近イベント（再接続時の差分送信用）
+	eventLogs map[uint]*eventLog
+	eventMu   sync.Mutex
+	publishMu sync.Mutex
+
+	// シーンごとの基準時刻（clock.sync用）。Redisに接続できないときだけ使う
+	epochs  map[string]time.Time
+	clockMu sync.Mutex
+
+	displayRepo  *repo.DisplayNodeRepository
+	entityRepo   *repo.SceneEntityRepository
+	stateRepo    *repo.EntityStateRepository
+	revisionRepo *repo.SceneRevisionRepository
+	clockRepo    *repo.SceneClockRepository
+	fightRepo    *repo.FightRepository
+	capacityRepo *repo.CapacityRepository
 }
 
 type Message struct {
 	Type string      `json:"type"`
+	Rev  int64       `json:"rev,omitempty"`
 	Data interface{} `json:"data"`
 }
 
-func NewHub() *Hub {
+func NewHub(
+	displayRepo *repo.DisplayNodeRepository,
+	entityRepo *repo.SceneEntityRepository,
+	stateRepo *repo.EntityStateRepository,
+	revisionRepo *repo.SceneRevisionRepository,
+	clockRepo *repo.SceneClockRepository,
+	fightRepo *repo.FightRepository,
+	capacityRepo *repo.CapacityRepository,
+	broadcaster Broadcaster,
+) *Hub {
 	return &Hub{
-		clients:    make(map[*Client]bool),
-		rooms:      make(map[string]map[*Client]bool),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		broadcast:  make(chan []byte),
+		clients:      make(map[*Client]bool),
+		rooms:        make(map[string]map[*Client]bool),
+		register:     make(chan *Client),
+		unregister:   make(chan *Client),
+		broadcast:    make(chan []byte),
+		eventLogs:    make(map[uint]*eventLog),
+		epochs:       make(map[string]time.Time),
+		displayRepo:  displayRepo,
+		entityRepo:   entityRepo,
+		stateRepo:    stateRepo,
+		revisionRepo: revisionRepo,
+		clockRepo:    clockRepo,
+		fightRepo:    fightRepo,
+		capacityRepo: capacityRepo,
+		broadcaster:  broadcaster,
 	}
 }
 
 func (h *Hub) Run() {
+	clockTicker := time.NewTicker(clockSyncPeriod)
+	defer clockTicker.Stop()
+
+	if h.broadcaster != nil {
+		go h.broadcaster.Subscribe(h.deliver)
+	}
+	go h.runFightTicker()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -69,6 +121,9 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.RUnlock()
+
+		case <-clockTicker.C:
+			h.broadcastClockSync()
 		}
 	}
 }
@@ -80,20 +135,54 @@ func (h *Hub) BroadcastToRoom(room string, message Message) {
 		return
 	}
 
+	h.publish(room, roomEvent{Data: data})
+}
+
+// broadcastDataToRoom は自インスタンスに接続中のルームのクライアントへ送る
+func (h *Hub) broadcastDataToRoom(room string, data []byte) {
 	h.mu.RLock()
 	if roomClients, exists := h.rooms[room]; exists {
 		for client := range roomClients {
-			select {
-			case client.send <- data:
-			default:
-				close(client.send)
-				delete(h.clients, client)
-			}
+			h.trySend(client, data)
 		}
 	}
 	h.mu.RUnlock()
 }
 
+// trySend は送信バッファが詰まったクライアントを切断する（h.muを保持して呼ぶこと）
+func (h *Hub) trySend(client *Client, data []byte) {
+	select {
+	case client.send <- data:
+	default:
+		// ロック保持中はマップを変更できないので登録解除はRunに任せる
+		go func() { h.unregister <- client }()
+	}
+}
+
+// SendToClient は特定のクライアントにのみメッセージを送る
+func (h *Hub) SendToClient(client *Client, message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	h.sendDataToClient(client, data)
+}
+
+func (h *Hub) sendDataToClient(client *Client, data []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	select {
+	case client.send <- data:
+	default:
+		log.Printf("Send buffer full, dropping message for %s", client.deviceKey)
+	}
+}
+
 func (h *Hub) GetRoomClients(room string) []*Client {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -110,7 +199,12 @@ func (h *Hub) GetRoomClients(room string) []*Client {
 func (h *Hub) MoveClientToRoom(client *Client, newRoom string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	
+
+	// 登録解除済み（sendがclose済み）のクライアントをルームに戻さない
+	if !h.clients[client] {
+		return
+	}
+
 	// 古いルームから削除
 	if oldRoom, exists := h.rooms[client.room]; exists {
 		delete(oldRoom, client)
@@ -134,14 +228,46 @@ func (h *Hub) BroadcastToAll(message Message) {
 		return
 	}
 
+	h.publish(allRoom, roomEvent{Data: data})
+}
+
+func (h *Hub) broadcastDataToAll(data []byte) {
+	h.mu.RLock()
+	for client := range h.clients {
+		h.trySend(client, data)
+	}
+	h.mu.RUnlock()
+}
+
+// DisconnectAPIKey は全インスタンスで指定したAPIキーの接続を切断する
+// 切断したクライアント数を返す。Broadcaster経由で他のインスタンスにも送った場合は全体の数が分からないのでnil
+func (h *Hub) DisconnectAPIKey(apiKeyID uint) *int {
+	if h.broadcaster == nil {
+		disconnected := h.disconnectAPIKeyLocal(apiKeyID)
+		return &disconnected
+	}
+	h.publish(controlRoom, roomEvent{Control: &controlEvent{
+		Action:   controlDisconnectAPIKey,
+		APIKeyID: apiKeyID,
+	}})
+	return nil
+}
+
+func (h *Hub) disconnectAPIKeyLocal(apiKeyID uint) int {
 	h.mu.RLock()
+	var targets []*Client
 	for client := range h.clients {
-		select {
-		case client.send <- data:
-		default:
-			close(client.send)
-			delete(h.clients, client)
+		if client.apiKeyID == apiKeyID {
+			targets = append(targets, client)
 		}
 	}
 	h.mu.RUnlock()
+
+	for _, client := range targets {
+		client.closeWithReason(websocket.ClosePolicyViolation, "api key revoked")
+	}
+	if len(targets) > 0 {
+		log.Printf("Disconnected %d client(s) using revoked API key %d", len(targets), apiKeyID)
+	}
+	return len(targets)
 }
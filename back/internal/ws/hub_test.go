@@ -0,0 +1,19 @@
+package ws
+
+import "testing"
+
+func TestMoveClientToRoomSkipsUnregistered(t *testing.T) {
+	h := NewHub(nil, nil, nil, nil, nil, nil, nil, nil)
+	client := &Client{hub: h, send: make(chan []byte, 1), room: "lobby"}
+
+	h.MoveClientToRoom(client, SceneRoom(1))
+	if len(h.GetRoomClients(SceneRoom(1))) != 0 {
+		t.Error("unregistered client was added to the room")
+	}
+
+	h.clients[client] = true
+	h.MoveClientToRoom(client, SceneRoom(1))
+	if got := h.GetRoomClients(SceneRoom(1)); len(got) != 1 || got[0] != client {
+		t.Errorf("room clients = %v, want [client]", got)
+	}
+}
@@ -0,0 +1,209 @@
+package ws
+
+import (
+	"culture-festival-backend/internal/domain"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// entity.removeのreason
+const (
+	RemoveReasonDeleted        = "deleted"
+	RemoveReasonArtworkDeleted = "artwork_deleted"
+	// シーンの定員を超えて退場した
+	RemoveReasonEvicted = "evicted"
+	// 審査で却下された
+	RemoveReasonRejected = "rejected"
+	// 表示後に取り下げられた
+	RemoveReasonTakenDown = "taken_down"
+)
+
+// PublishSceneResyncで送るscene.snapshotのreason
+const (
+	ResyncReasonSnapshotRestore = "snapshot_restore"
+	ResyncReasonSoftReset       = "soft_reset"
+	ResyncReasonResetUndo       = "reset_undo"
+)
+
+// EntityPayload はentity.addやscene.snapshotで送るエンティティ情報を作る
+// entity.Artworkがプリロードされている必要がある
+func EntityPayload(entity *domain.SceneEntity) map[string]interface{} {
+	return map[string]interface{}{
+		"entity_id":   entity.ID,
+		"artwork_id":  entity.ArtworkID,
+		"artwork_url": fmt.Sprintf("/download/%s", entity.Artwork.QRToken),
+		"init": map[string]interface{}{
+			"x":     entity.InitX,
+			"y":     entity.InitY,
+			"vx":    entity.InitVX,
+			"vy":    entity.InitVY,
+			"angle": entity.InitAngle,
+			"scale": entity.InitScale,
+		},
+		"animation_kind":   entity.AnimationKind,
+		"animation_params": entity.AnimationParams,
+		"seed":             entity.RNGSeed,
+	}
+}
+
+// PublishSceneEvent はシーンのリビジョン（イベントのシーケンス番号）を進めてからルームへ配信する
+// クライアントはscene.snapshotのrev以下のイベントを破棄でき、再接続時はlast_seqで差分を受け取れる
+func (h *Hub) PublishSceneEvent(sceneID uint, message Message) {
+	if publisher, ok := h.broadcaster.(SceneEventPublisher); ok {
+		data, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
+			return
+		}
+		if _, err = publisher.PublishSceneEvent(SceneRoom(sceneID), sceneID, data); err == nil {
+			return
+		}
+		// Redisが落ちていれば自インスタンスのクライアントにだけ届ける
+		log.Printf("Failed to publish scene event, delivering locally: scene_id=%d, error=%v", sceneID, err)
+	}
+
+	// 採番と配信の順序を揃える（自インスタンス内でのみ有効。複数インスタンスではSceneEventPublisherを使う）
+	h.publishMu.Lock()
+	defer h.publishMu.Unlock()
+
+	rev, err := h.revisionRepo.Incr(sceneID)
+	if err != nil {
+		log.Printf("Failed to increment scene revision: scene_id=%d, error=%v", sceneID, err)
+	}
+	message.Rev = rev
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	h.publish(SceneRoom(sceneID), roomEvent{SceneID: sceneID, Seq: rev, Data: data})
+}
+
+// PublishEntityRemove は削除したエンティティの報告済みの状態を消し、含んでいたシーンのルームにentity.removeを送る
+// 対戦相手が残されたら組み直す
+func (h *Hub) PublishEntityRemove(entity *domain.SceneEntity, reason string) {
+	if err := h.stateRepo.Delete(entity.SceneID, entity.ID); err != nil {
+		log.Printf("Failed to clear entity state: entity_id=%d, error=%v", entity.ID, err)
+	}
+
+	h.PublishSceneEvent(entity.SceneID, Message{
+		Type: "entity.remove",
+		Data: map[string]interface{}{
+			"entity_id":  entity.ID,
+			"artwork_id": entity.ArtworkID,
+			"reason":     reason,
+		},
+	})
+
+	if entity.AnimationKind == "spin_fight" {
+		h.ReconcileFights(entity.SceneID)
+	}
+}
+
+// sendSceneSnapshot はシーンの全エンティティと最新の揮発状態をクライアントに送る
+// ルーム参加後に呼ぶことで、取得中に発生したイベントも取りこぼさない
+func (h *Hub) sendSceneSnapshot(client *Client, scene *domain.Scene) {
+	rev, err := h.revisionRepo.Get(scene.ID)
+	if err != nil {
+		log.Printf("Failed to get scene revision: scene_id=%d, error=%v", scene.ID, err)
+	}
+
+	data, err := h.sceneSnapshotData(scene)
+	if err != nil {
+		log.Printf("Failed to load scene entities for snapshot: scene_id=%d, error=%v", scene.ID, err)
+		return
+	}
+
+	h.SendToClient(client, Message{
+		Type: "scene.snapshot",
+		Rev:  rev,
+		Data: data,
+	})
+}
+
+// PublishSceneResync はシーン全体をscene.snapshotとしてルームの全ディスプレイに配信する
+// スナップショットの復元など、差分イベントでは表せない変更の後に使う（reasonはdata.reasonに入る）
+func (h *Hub) PublishSceneResync(scene *domain.Scene, reason string) {
+	data, err := h.sceneSnapshotData(scene)
+	if err != nil {
+		log.Printf("Failed to load scene entities for resync: scene_id=%d, error=%v", scene.ID, err)
+		return
+	}
+	data["reason"] = reason
+
+	h.PublishSceneEvent(scene.ID, Message{
+		Type: "scene.snapshot",
+		Data: data,
+	})
+}
+
+// ForgetScene は削除したシーンのRedis上の状態（リビジョン・基準時刻・エンティティの状態）と
+// 各インスタンスのイベントログを消す。同じIDが再利用されても古いseqで差分送信しないように
+func (h *Hub) ForgetScene(sceneID uint) {
+	if err := h.revisionRepo.Delete(sceneID); err != nil {
+		log.Printf("Failed to clear scene revision: scene_id=%d, error=%v", sceneID, err)
+	}
+	if err := h.clockRepo.Delete(SceneRoom(sceneID)); err != nil {
+		log.Printf("Failed to clear scene epoch: scene_id=%d, error=%v", sceneID, err)
+	}
+	if err := h.stateRepo.DeleteBySceneID(sceneID); err != nil {
+		log.Printf("Failed to clear entity state: scene_id=%d, error=%v", sceneID, err)
+	}
+
+	h.publish(controlRoom, roomEvent{Control: &controlEvent{
+		Action:  controlForgetScene,
+		SceneID: sceneID,
+	}})
+}
+
+func (h *Hub) forgetSceneLocal(sceneID uint) {
+	h.eventMu.Lock()
+	delete(h.eventLogs, sceneID)
+	h.eventMu.Unlock()
+
+	h.clockMu.Lock()
+	delete(h.epochs, SceneRoom(sceneID))
+	h.clockMu.Unlock()
+}
+
+func (h *Hub) sceneSnapshotData(scene *domain.Scene) (map[string]interface{}, error) {
+	entities, err := h.entityRepo.GetBySceneID(scene.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := h.stateRepo.GetBySceneID(scene.ID)
+	if err != nil {
+		// Redisが落ちていても初期値だけで描画を継続できる
+		log.Printf("Failed to load entity state for snapshot: scene_id=%d, error=%v", scene.ID, err)
+	}
+
+	matches, err := h.fightRepo.ListActiveBySceneID(scene.ID)
+	if err != nil {
+		log.Printf("Failed to load fights for snapshot: scene_id=%d, error=%v", scene.ID, err)
+	}
+	fights := make([]map[string]interface{}, 0, len(matches))
+	for i := range matches {
+		fights = append(fights, fightPayload(&matches[i]))
+	}
+
+	payloads := make([]map[string]interface{}, 0, len(entities))
+	for i := range entities {
+		payload := EntityPayload(&entities[i])
+		if state, ok := states[entities[i].ID]; ok {
+			payload["state"] = state
+		}
+		payloads = append(payloads, payload)
+	}
+
+	return map[string]interface{}{
+		"scene_id": scene.ID,
+		"width":    scene.Width,
+		"height":   scene.Height,
+		"entities": payloads,
+		"fights":   fights,
+	}, nil
+}
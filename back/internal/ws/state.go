@@ -0,0 +1,68 @@
+package ws
+
+import (
+	"culture-festival-backend/internal/domain"
+	"log"
+	"strings"
+	"time"
+)
+
+// viewport外からの報告は、担当ノードの報告がこの時間途絶えた場合のみ採用する
+const stateReportFallbackAfter = 2 * time.Second
+
+// handleStateReport はエンティティを自分のviewport内に表示しているノードを
+// 権威ある報告者としてRedisのentity_stateを更新する
+func (h *Hub) handleStateReport(client *Client, data StateReportData) {
+	node := h.clientNode(client)
+	if node == nil || node.SceneID == nil {
+		// display.hello前やシーン未割り当ての報告は無視
+		return
+	}
+	sceneID := *node.SceneID
+	reportedBy := strings.TrimSpace(node.DeviceKey)
+
+	if !viewportContains(node, data.X, data.Y) {
+		current, err := h.stateRepo.Get(sceneID, data.EntityID)
+		if err == nil && current.ReportedBy != reportedBy &&
+			time.Since(current.UpdatedAt) < stateReportFallbackAfter {
+			return
+		}
+	}
+
+	state := &domain.EntityState{
+		EntityID:   data.EntityID,
+		SceneID:    sceneID,
+		X:          data.X,
+		Y:          data.Y,
+		VX:         data.VX,
+		VY:         data.VY,
+		Angle:      data.Angle,
+		Scale:      data.Scale,
+		TS:         data.TS,
+		ReportedBy: reportedBy,
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := h.stateRepo.Save(state); err != nil {
+		log.Printf("Failed to save entity state: entity=%d, error=%v", data.EntityID, err)
+	}
+}
+
+func (h *Hub) clientNode(client *Client) *domain.DisplayNode {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return client.node
+}
+
+func (h *Hub) setClientNode(client *Client, node *domain.DisplayNode) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// deviceKeyはGetClientsByDeviceKeyがh.mu下で読むので、nodeと一緒にロック中に書く
+	client.deviceKey = strings.TrimSpace(node.DeviceKey)
+	client.node = node
+}
+
+func viewportContains(node *domain.DisplayNode, x, y float64) bool {
+	return x >= float64(node.ViewportX) && x < float64(node.ViewportX+node.ViewportW) &&
+		y >= float64(node.ViewportY) && y < float64(node.ViewportY+node.ViewportH)
+}
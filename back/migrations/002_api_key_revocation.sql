@@ -0,0 +1,5 @@
+-- APIキーの失効（ローテーション）対応
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_role ON api_keys(role);
@@ -0,0 +1,5 @@
+-- 開発用のデフォルトディスプレイノード（display.helloで使用）
+
+INSERT INTO display_nodes (scene_id, name, viewport_x, viewport_y, viewport_w, viewport_h, scale, pixel_width, pixel_height, device_key) VALUES
+(1, 'display_dev', 0, 0, 1920, 1080, 1, 1920, 1080, 'display_dev_key_12345')
+ON CONFLICT (device_key) DO NOTHING;
@@ -0,0 +1,10 @@
+-- シーンごとのアニメーション割り当てポリシー
+
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS animation_policy VARCHAR(32) NOT NULL DEFAULT 'fixed';
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS animation_policy_params JSONB;
+-- round_robin用のカウンタ（UPDATE ... RETURNINGで原子的に進める）
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS animation_rr_index BIGINT NOT NULL DEFAULT 0;
+
+-- 従来通りデフォルトは常にspin_fight
+UPDATE scenes SET animation_policy_params = '{"kind": "spin_fight"}'
+WHERE animation_policy = 'fixed' AND animation_policy_params IS NULL;
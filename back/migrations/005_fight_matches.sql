@@ -0,0 +1,20 @@
+-- spin_fightの対戦と戦績
+
+CREATE TABLE IF NOT EXISTS fight_matches (
+    id BIGSERIAL PRIMARY KEY,
+    scene_id BIGINT NOT NULL REFERENCES scenes(id),
+    -- エンティティやアートワークが消えても戦績は残すため外部キーは張らない
+    entity_a_id BIGINT NOT NULL,
+    entity_b_id BIGINT NOT NULL,
+    artwork_a_id BIGINT NOT NULL,
+    artwork_b_id BIGINT NOT NULL,
+    winner_entity_id BIGINT,
+    winner_artwork_id BIGINT,
+    end_reason VARCHAR(32),
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ends_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fight_matches_scene_active ON fight_matches(scene_id) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_fight_matches_winner_artwork ON fight_matches(winner_artwork_id);
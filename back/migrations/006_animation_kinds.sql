@@ -0,0 +1,41 @@
+-- アニメーション種類をenumからレジストリテーブルへ移行
+
+CREATE TABLE IF NOT EXISTS animation_kinds (
+    name VARCHAR(32) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    param_schema JSONB NOT NULL DEFAULT '{}',
+    default_params JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO animation_kinds (name, description, param_schema, default_params) VALUES
+('pulsate', '拡大縮小を繰り返す脈動アニメーション',
+ '{"type": "object", "properties": {"amplitude": {"type": "number", "minimum": 0, "maximum": 1}, "speed": {"type": "number", "minimum": 0, "maximum": 10}}}',
+ '{"amplitude": 0.2, "speed": 1.5}'),
+('disperse', 'パーティクルに分解して再結合',
+ '{"type": "object", "properties": {"particle_interval": {"type": "number", "minimum": 0.01, "maximum": 5}, "particle_count": {"type": "integer", "minimum": 1, "maximum": 50}}}',
+ '{"particle_interval": 0.1, "particle_count": 3}'),
+('explode', '爆散して消滅または再生',
+ '{"type": "object", "properties": {"particle_count": {"type": "integer", "minimum": 1, "maximum": 100}, "shake": {"type": "number", "minimum": 0, "maximum": 100}}}',
+ '{"particle_count": 20, "shake": 10}'),
+('spin_fight', '近傍のペアと高速回転して衝突（ベイブレード風）',
+ '{"type": "object", "properties": {"spin_speed": {"type": "number", "minimum": 0, "maximum": 20}, "attract": {"type": "number", "minimum": 0, "maximum": 5}}}',
+ '{"spin_speed": 2, "attract": 0.5}'),
+('stream_in', '画面端から流れてくる流入アニメーション',
+ '{"type": "object", "properties": {"speed": {"type": "number", "minimum": 0, "maximum": 2000}}}',
+ '{"speed": 200}')
+ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE scene_entities ALTER COLUMN animation_kind TYPE VARCHAR(32) USING animation_kind::text;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_scene_entities_animation_kind') THEN
+        ALTER TABLE scene_entities ADD CONSTRAINT fk_scene_entities_animation_kind
+            FOREIGN KEY (animation_kind) REFERENCES animation_kinds(name);
+    END IF;
+END $$;
+-- エンティティごとのパラメータ上書き（default_paramsにマージして使う）
+ALTER TABLE scene_entities ADD COLUMN IF NOT EXISTS animation_params JSONB;
+
+DROP TYPE IF EXISTS animation_kind;
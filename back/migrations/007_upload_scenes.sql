@@ -0,0 +1,6 @@
+-- アップロードの振り分け先シーン（複数可）
+
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS accept_uploads BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 従来通りデフォルトシーンに振り分ける
+UPDATE scenes SET accept_uploads = TRUE WHERE id = 1;
@@ -0,0 +1,3 @@
+-- シーン削除時にディスプレイノードを残して切り離せるようにする
+
+ALTER TABLE display_nodes ALTER COLUMN scene_id DROP NOT NULL;
@@ -0,0 +1,11 @@
+-- 名前付きシーンスナップショット
+
+CREATE TABLE IF NOT EXISTS scene_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    scene_id BIGINT NOT NULL REFERENCES scenes(id),
+    name VARCHAR(100) NOT NULL,
+    entity_count INTEGER NOT NULL,
+    entities JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (scene_id, name)
+);
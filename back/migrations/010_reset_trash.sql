@@ -0,0 +1,6 @@
+-- ハードリセットで退避したエンティティ（ゴミ箱）をスナップショットとして保持する
+
+ALTER TABLE scene_snapshots ADD COLUMN IF NOT EXISTS kind VARCHAR(16) NOT NULL DEFAULT 'manual';
+ALTER TABLE scene_snapshots ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_scene_snapshots_kind ON scene_snapshots(scene_id, kind);
@@ -0,0 +1,26 @@
+-- シーンの定員と超過時の退場ポリシー
+
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS max_entities INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS eviction_policy VARCHAR(16) NOT NULL DEFAULT 'oldest';
+
+-- least_liked用のいいね数
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS like_count INTEGER NOT NULL DEFAULT 0;
+
+-- rotateで画面外に退避したエンティティ（空きができたら古い順に戻す）
+CREATE TABLE IF NOT EXISTS queued_entities (
+    id BIGSERIAL PRIMARY KEY,
+    scene_id BIGINT NOT NULL REFERENCES scenes(id),
+    artwork_id BIGINT NOT NULL REFERENCES artworks(id) ON DELETE CASCADE,
+    init_x DOUBLE PRECISION NOT NULL,
+    init_y DOUBLE PRECISION NOT NULL,
+    init_vx DOUBLE PRECISION NOT NULL,
+    init_vy DOUBLE PRECISION NOT NULL,
+    init_angle DOUBLE PRECISION NOT NULL DEFAULT 0,
+    init_scale DOUBLE PRECISION NOT NULL DEFAULT 0.25,
+    animation_kind VARCHAR(32) NOT NULL REFERENCES animation_kinds(name),
+    animation_params JSONB,
+    rng_seed BIGINT NOT NULL,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_queued_entities_scene ON queued_entities(scene_id, queued_at);
@@ -0,0 +1,19 @@
+-- アートワークの審査（承認されるまで承認制のシーンには出さない）
+
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'approved';
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS moderation_reason VARCHAR(500);
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS moderated_by BIGINT REFERENCES api_keys(id);
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_artworks_status ON artworks(status);
+
+ALTER TABLE scenes ADD COLUMN IF NOT EXISTS require_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- 承認待ちのアートワークを承認後に追加するシーン
+CREATE TABLE IF NOT EXISTS pending_placements (
+    id BIGSERIAL PRIMARY KEY,
+    artwork_id BIGINT NOT NULL REFERENCES artworks(id) ON DELETE CASCADE,
+    scene_id BIGINT NOT NULL REFERENCES scenes(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (artwork_id, scene_id)
+);
@@ -0,0 +1,17 @@
+-- 表示中のアートワークの取り下げと履歴（アセットは確認用に残す）
+
+ALTER TABLE artworks ADD COLUMN IF NOT EXISTS taken_down_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS artwork_takedowns (
+    id BIGSERIAL PRIMARY KEY,
+    -- 証跡として残すため、アートワークが削除されても消さない
+    artwork_id BIGINT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    reason VARCHAR(500),
+    performed_by BIGINT REFERENCES api_keys(id),
+    -- 取り下げ時に表示されていた（復帰時に戻した）シーン
+    scene_ids JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_artwork_takedowns_artwork ON artwork_takedowns(artwork_id, created_at);
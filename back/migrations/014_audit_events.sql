@@ -0,0 +1,23 @@
+-- 状態を変更するリクエストの監査ログ
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    -- キーが失効・削除されても誰の操作か分かるよう名前とロールも残す
+    api_key_id BIGINT,
+    api_key_name VARCHAR(100),
+    role VARCHAR(16),
+    method VARCHAR(8) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    path VARCHAR(500) NOT NULL,
+    -- {"scene_id": [3], "entity_id": [12]} の形（@> で絞り込む）
+    targets JSONB NOT NULL DEFAULT '{}',
+    summary TEXT,
+    status INTEGER NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    client_ip VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_api_key ON audit_events(api_key_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_targets ON audit_events USING GIN (targets);
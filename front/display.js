@@ -33,8 +33,19 @@ class DisplaySystem {
     };
 
     // 設定
-    this.sceneId = 1; // デフォルトシーン
-    this.deviceKey = "display_dev_key_12345";
+    const params = new URLSearchParams(window.location.search);
+    this.sceneId = 1; // デフォルトシーン（display.configで上書きされる）
+    this.apiKey = "display_dev_key_12345";
+    // 壁面構成ではURLの ?device_key= で各ディスプレイを識別する
+    this.deviceKey = params.get("device_key") || "display_dev_key_12345";
+    // サーバとの時計同期（clock.sync / clock.pong）
+    this.clock = { t0: null, tickMs: 16, offset: 0, latency: 0 };
+
+    // シーンのリビジョン（scene.snapshot以前のイベントを破棄する）
+    this.sceneRev = 0;
+    this.pendingEvents = null;
+    // アニメーション種類ごとのデフォルトパラメータ（/api/animation-kinds）
+    this.animationDefaults = {};
     this.viewport = {
       x: 0,
       y: 0,
@@ -164,13 +175,71 @@ class DisplaySystem {
 
   init() {
     console.log("⚙️ Starting initialization...");
+    this.loadAnimationKinds();
     this.setupCanvas();
     this.setupWebSocket();
     this.setupEventListeners();
     this.startAnimationLoop();
+    this.startStateReporting();
     console.log("✅ Initialization complete");
   }
 
+  // アニメーション種類ごとのデフォルトパラメータを取得
+  async loadAnimationKinds() {
+    try {
+      const response = await fetch("/api/animation-kinds");
+      if (!response.ok) {
+        throw new Error(`HTTP ${response.status}`);
+      }
+      const kinds = await response.json();
+      kinds.forEach((kind) => {
+        this.animationDefaults[kind.name] = kind.default_params || {};
+      });
+      console.log("🎞️ Animation kinds loaded:", Object.keys(this.animationDefaults));
+    } catch (error) {
+      console.warn("⚠️ Failed to load animation kinds, using built-in defaults:", error);
+    }
+  }
+
+  // エンティティの上書き → レジストリのデフォルト → 組み込み値 の順でパラメータを引く
+  animationParam(entity, name, fallback) {
+    if (entity.animationParams && entity.animationParams[name] !== undefined) {
+      return entity.animationParams[name];
+    }
+    const defaults = this.animationDefaults[entity.animationKind];
+    if (defaults && defaults[name] !== undefined) {
+      return defaults[name];
+    }
+    return fallback;
+  }
+
+  // 表示中のエンティティ状態を定期的にサーバへ報告（Redisのentity_stateに保存される）
+  startStateReporting() {
+    setInterval(() => {
+      if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+        return;
+      }
+      const ts = Date.now();
+      this.entities.forEach((entity) => {
+        this.ws.send(
+          JSON.stringify({
+            type: "state.report",
+            data: {
+              entity_id: entity.id,
+              x: entity.x,
+              y: entity.y,
+              vx: entity.vx,
+              vy: entity.vy,
+              angle: entity.angle,
+              scale: entity.scale,
+              ts: ts,
+            },
+          })
+        );
+      });
+    }, 1000);
+  }
+
   setupCanvas() {
     console.log("🎨 Setting up canvas...");
     this.canvas.width = this.viewport.width;
@@ -189,7 +258,7 @@ class DisplaySystem {
 
   setupWebSocket() {
     const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
-    const wsUrl = `${protocol}//${window.location.host}/ws`;
+    const wsUrl = `${protocol}//${window.location.host}/ws?api_key=${encodeURIComponent(this.apiKey)}`;
 
     console.log(`🔌 Connecting to WebSocket: ${wsUrl}`);
 
@@ -199,8 +268,12 @@ class DisplaySystem {
       console.log("✅ WebSocket connected");
       this.isConnected = true;
       this.updateConnectionStatus("接続済み");
+      // 初回接続時はscene.snapshotが届くまで受信イベントを保留する
+      // 再接続時はlast_seqを送り、取りこぼした分だけ差分で受け取る
+      if (this.sceneRev === 0) {
+        this.pendingEvents = [];
+      }
       this.sendHello();
-      this.loadExistingEntities();
     };
 
     this.ws.onmessage = (event) => {
@@ -232,6 +305,7 @@ class DisplaySystem {
       data: {
         display_key: this.deviceKey,
         scene_id: this.sceneId,
+        last_seq: this.sceneRev,
         caps: {
           w: this.viewport.width,
           h: this.viewport.height,
@@ -247,18 +321,46 @@ class DisplaySystem {
   handleMessage(message) {
     console.log(`📨 WebSocket message received:`, message.type);
 
+    if (message.rev && message.type !== "scene.snapshot") {
+      if (this.pendingEvents !== null) {
+        this.pendingEvents.push(message);
+        return;
+      }
+      if (message.rev <= this.sceneRev) {
+        console.log(`  ⏭️ Skipping stale event rev=${message.rev}`);
+        return;
+      }
+      this.sceneRev = message.rev;
+    }
+
     switch (message.type) {
+      case "scene.snapshot":
+        console.log(`  ➡️ Applying snapshot rev=${message.rev || 0}`);
+        this.applySnapshot(message);
+        break;
       case "entity.add":
         console.log(`  ➡️ Adding entity:`, message.data);
         this.addEntity(message.data);
         break;
+      case "entity.update":
+        console.log(`  ➡️ Updating entity:`, message.data.entity_id, message.data.changed);
+        this.updateEntity(message.data);
+        break;
       case "entity.remove":
-        console.log(`  ➡️ Removing entity:`, message.data.entity_id);
+        console.log(`  ➡️ Removing entity:`, message.data.entity_id, message.data.reason);
         this.removeEntity(message.data.entity_id);
         break;
-      case "entity.delete":
-        console.log(`  ➡️ Deleting entity by artwork:`, message.data.artwork_id);
-        this.removeEntityByArtworkId(message.data.artwork_id);
+      case "fight.start":
+        console.log(`  ➡️ Fight start:`, message.data.a, message.data.b);
+        this.startFight(message.data);
+        break;
+      case "fight.end":
+        console.log(`  ➡️ Fight end:`, message.data.winner, message.data.reason);
+        this.endFight(message.data);
+        break;
+      case "scene.update":
+        console.log(`  ➡️ Scene updated:`, message.data);
+        this.sceneSize = { width: message.data.width, height: message.data.height };
         break;
       case "scene.reset":
         console.log(`  ➡️ Resetting scene`);
@@ -266,20 +368,65 @@ class DisplaySystem {
         break;
       case "display.config":
         console.log(`  ➡️ Updating viewport:`, message.data.viewport);
+        if (message.data.scene_id === null) {
+          // シーンが削除されて切り離された。再割り当てされるまで何も表示しない
+          console.log(`  ⏸️ Display detached from scene`);
+          this.sceneId = null;
+          this.sceneRev = 0;
+          this.pendingEvents = null;
+          this.resetScene();
+        } else if (message.data.scene_id !== this.sceneId) {
+          // 別シーンへ移動された場合は続くscene.snapshotで入れ替える
+          this.sceneId = message.data.scene_id;
+          this.pendingEvents = [];
+        }
         this.updateViewport(message.data.viewport);
         break;
       case "clock.sync":
         console.log(`  ➡️ Clock sync:`, message.data);
         this.syncClock(message.data);
         break;
+      case "clock.pong":
+        this.handleClockPong(message.data);
+        break;
       default:
         console.warn(`  ⚠️ Unknown message type:`, message.type);
     }
   }
 
+  // サーバから受け取ったシーン全体で表示を置き換え、保留中のイベントを適用する
+  applySnapshot(message) {
+    this.resetScene();
+    this.sceneRev = message.rev || 0;
+    this.sceneSize = { width: message.data.width, height: message.data.height };
+
+    message.data.entities.forEach((data) => {
+      this.addEntity(data);
+      const entity = this.entities.get(data.entity_id);
+      if (entity && data.state) {
+        entity.x = data.state.x;
+        entity.y = data.state.y;
+        entity.vx = data.state.vx;
+        entity.vy = data.state.vy;
+        entity.angle = data.state.angle;
+        entity.scale = data.state.scale;
+      }
+    });
+    (message.data.fights || []).forEach((fight) => this.startFight(fight));
+
+    const pending = this.pendingEvents || [];
+    this.pendingEvents = null;
+    pending.forEach((event) => this.handleMessage(event));
+  }
+
   addEntity(data) {
     console.log(`➕ addEntity called with:`, data);
 
+    if (this.entities.has(data.entity_id)) {
+      console.warn(`  ⚠️ Entity ${data.entity_id} already exists, skipping`);
+      return;
+    }
+
     const entity = {
       id: data.entity_id,
       artworkId: data.artwork_id, // 作品IDを追加
@@ -292,6 +439,7 @@ class DisplaySystem {
       scale: data.init.scale,
       initScale: data.init.scale,
       animationKind: data.animation_kind,
+      animationParams: data.animation_params || null,
       seed: data.seed,
       element: null,
       image: null,
@@ -367,68 +515,6 @@ class DisplaySystem {
     img.src = entity.artworkUrl;
   }
 
-  loadExistingEntities() {
-    console.log(`🔄 Loading existing entities for scene ${this.sceneId}...`);
-    fetch(`/api/scenes/${this.sceneId}`)
-      .then((response) => {
-        if (!response.ok) {
-          throw new Error(`Failed to load scene: ${response.status}`);
-        }
-        return response.json();
-      })
-      .then((scene) => {
-        console.log(`📦 Scene data received:`, scene);
-
-        if (!scene.entities || !Array.isArray(scene.entities)) {
-          console.warn(`⚠️ No entities in scene or invalid format`);
-          return;
-        }
-
-        console.log(`📋 Found ${scene.entities.length} entities in scene`);
-
-        scene.entities.forEach((entity, index) => {
-          console.log(`  [${index}] Entity ${entity.id}:`, {
-            hasArtwork: !!entity.artwork,
-            animationKind: entity.animation_kind,
-            position: `(${entity.init_x}, ${entity.init_y})`
-          });
-
-          if (this.entities.has(entity.id)) {
-            console.warn(`  ⚠️ Entity ${entity.id} already exists, skipping`);
-            return;
-          }
-
-          if (!entity.artwork) {
-            console.warn(`  ⚠️ Entity ${entity.id} has no artwork, skipping`);
-            return;
-          }
-
-          this.addEntity({
-            entity_id: entity.id,
-            artwork_id: entity.artwork_id,
-            artwork_url: `/download/${entity.artwork.qr_token}`,
-            init: {
-              x: entity.init_x,
-              y: entity.init_y,
-              vx: entity.init_vx,
-              vy: entity.init_vy,
-              angle: entity.init_angle,
-              scale:
-                typeof entity.init_scale === "number" ? entity.init_scale : 0.25,
-            },
-            animation_kind: entity.animation_kind,
-            seed: entity.rng_seed,
-          });
-        });
-
-        console.log(`✅ Finished loading entities. Total entities: ${this.entities.size}`);
-      })
-      .catch((error) => {
-        console.error("❌ Failed to load existing entities:", error);
-        this.updateConnectionStatus("初期データ取得失敗");
-      });
-  }
-
   createEntityElement(entity) {
     const div = document.createElement("div");
     div.className = "entity";
@@ -472,6 +558,39 @@ class DisplaySystem {
     return div;
   }
 
+  // ops画面での変更を反映（位置・速度を変えた場合は初期値から再開）
+  updateEntity(data) {
+    const entity = this.entities.get(data.entity_id);
+    if (!entity) {
+      this.addEntity(data);
+      return;
+    }
+
+    const changed = data.changed || [];
+    if (changed.some((field) => ["init_x", "init_y", "init_vx", "init_vy"].includes(field))) {
+      entity.x = data.init.x;
+      entity.y = data.init.y;
+      entity.vx = data.init.vx;
+      entity.vy = data.init.vy;
+    }
+    entity.angle = data.init.angle;
+    entity.scale = data.init.scale;
+    entity.initScale = data.init.scale;
+    // spin_fightのままなら対戦相手はサーバのfight.endまで維持する
+    const keepFight = entity.animationKind === "spin_fight" && data.animation_kind === "spin_fight";
+    const fightTarget = keepFight && entity.animationState ? entity.animationState.fightTarget : null;
+    entity.animationKind = data.animation_kind;
+    entity.animationParams = data.animation_params || null;
+    entity.seed = data.seed;
+    entity.animationState = {
+      phase: 0,
+      lastParticleTime: 0,
+      fightTarget,
+      fightPhase: 0,
+      streamStartTime: Date.now() * 0.001,
+    };
+  }
+
   removeEntity(entityId) {
     const entity = this.entities.get(entityId);
     if (entity && entity.element) {
@@ -483,23 +602,6 @@ class DisplaySystem {
     console.log("Entity removed:", entityId);
   }
 
-  removeEntityByArtworkId(artworkId) {
-    // 作品IDに基づいてエンティティを削除
-    const entitiesToRemove = [];
-
-    this.entities.forEach((entity, entityId) => {
-      if (entity.artworkId === artworkId) {
-        entitiesToRemove.push(entityId);
-      }
-    });
-
-    entitiesToRemove.forEach((entityId) => {
-      this.removeEntity(entityId);
-    });
-
-    console.log("Entities removed by artwork ID:", artworkId);
-  }
-
   resetScene() {
     this.entities.forEach((entity) => {
       if (entity.element) {
@@ -618,6 +720,9 @@ class DisplaySystem {
         // 削除リクエスト送信
         const response = await fetch(`/api/scenes/${this.sceneId}/entities/${entityId}`, {
           method: 'DELETE',
+          headers: {
+            'Authorization': `Bearer ${this.apiKey}`,
+          },
         });
 
         if (response.ok) {
@@ -640,8 +745,42 @@ class DisplaySystem {
   }
 
   syncClock(data) {
-    // 時計同期（将来の実装）
-    console.log("Clock sync:", data);
+    this.clock.t0 = data.t0;
+    this.clock.tickMs = data.tick_ms;
+    // 受信のたびにRTTを測ってオフセットを更新
+    this.sendClockPing();
+  }
+
+  sendClockPing() {
+    if (!this.ws || this.ws.readyState !== WebSocket.OPEN) {
+      return;
+    }
+    this.ws.send(
+      JSON.stringify({ type: "clock.ping", data: { client_ts: Date.now() } })
+    );
+  }
+
+  // NTP方式: offset = ((t1 - t0) + (t2 - t3)) / 2
+  handleClockPong(data) {
+    const t3 = Date.now();
+    const t0 = data.client_ts;
+    const t1 = data.server_recv_ts;
+    const t2 = data.server_send_ts;
+    const rtt = t3 - t0 - (t2 - t1);
+
+    this.clock.offset = (t1 - t0 + (t2 - t3)) / 2;
+    this.clock.latency = rtt / 2;
+    console.log(
+      `⏱️ Clock offset: ${this.clock.offset.toFixed(1)}ms, latency: ${this.clock.latency.toFixed(1)}ms`
+    );
+  }
+
+  // サーバ時刻基準でのシーン経過時間（ミリ秒）
+  sceneTime() {
+    if (this.clock.t0 === null) {
+      return 0;
+    }
+    return Date.now() + this.clock.offset - this.clock.t0;
   }
 
   startAnimationLoop() {
@@ -681,9 +820,6 @@ class DisplaySystem {
       return;
     }
 
-    // spin_fightエンティティのマッチングを一括処理（フレームごとに1回のみ）
-    this.matchSpinFightEntities();
-
     let spin_fight_count = 0;
     this.entities.forEach((entity) => {
       if (entity.animationKind === "spin_fight") {
@@ -716,97 +852,30 @@ class DisplaySystem {
     this.updateParticles(deltaTime);
   }
 
-  // spin_fightエンティティのマッチングを一括処理
-  matchSpinFightEntities() {
-    const spinFightEntities = [];
-
-    // spin_fightエンティティを収集
-    this.entities.forEach((entity, id) => {
-      if (entity.animationKind === "spin_fight") {
-        if (!entity.animationState) {
-          console.error(`❌ Entity ${id} is spin_fight but has NO animationState!`);
-          // 緊急修正: animationStateを作成
-          entity.animationState = {
-            phase: 0,
-            lastParticleTime: 0,
-            fightTarget: null,
-            fightPhase: 0,
-            streamStartTime: Date.now() * 0.001,
-          };
-          console.log(`🔧 Created missing animationState for entity ${id}`);
-        }
-        spinFightEntities.push({ id, entity });
-      }
-    });
-
-    if (spinFightEntities.length === 0) return;
-
-    // ログは10秒に1回だけ
-    const now = Date.now();
-    if (!this.lastMatchLog || now - this.lastMatchLog > 10000) {
-      console.log(`🎯 matchSpinFightEntities: Found ${spinFightEntities.length} spin_fight entities`);
-      spinFightEntities.forEach(({ id, entity }) => {
-        console.log(`  - Entity ${id}: hasAnimationState=${!!entity.animationState}, position=(${Math.round(entity.x)}, ${Math.round(entity.y)})`);
-      });
-      this.lastMatchLog = now;
+  // サーバが組んだspin_fightの対戦を両エンティティに設定
+  startFight(data) {
+    const a = this.entities.get(data.a);
+    const b = this.entities.get(data.b);
+    if (!a || !b || !a.animationState || !b.animationState) {
+      console.warn(`⚠️ fight.start for unknown entities: ${data.a} ↔ ${data.b}`);
+      return;
     }
+    a.animationState.fightTarget = data.b;
+    b.animationState.fightTarget = data.a;
+    console.log(`🎯 spin_fight matched: ${data.a} ↔ ${data.b} (match ${data.match_id})`);
+  }
 
-    // マッチング処理
-    for (let i = 0; i < spinFightEntities.length; i++) {
-      const { id: id1, entity: entity1 } = spinFightEntities[i];
-      const state1 = entity1.animationState;
-
-      // 既にターゲットがあり、相互参照が成立している場合はスキップ
-      if (state1.fightTarget) {
-        const target = this.entities.get(state1.fightTarget);
-        if (target && target.animationState && target.animationState.fightTarget === id1) {
-          continue; // 既に正しくマッチングされている
-        }
-      }
-
-      // ターゲットを探す
-      let closestId = null;
-      let closestDistance = Infinity;
-
-      for (let j = 0; j < spinFightEntities.length; j++) {
-        if (i === j) continue;
-
-        const { id: id2, entity: entity2 } = spinFightEntities[j];
-
-        // animationStateの存在を確認
-        if (!entity2.animationState) {
-          console.warn(`⚠️ Entity ${id2} has no animationState in matchSpinFightEntities`);
-          continue;
-        }
-
-        const state2 = entity2.animationState;
-
-        // 相手が既に他のターゲットを持っている場合はスキップ
-        if (state2.fightTarget && state2.fightTarget !== id1) {
-          const otherTarget = this.entities.get(state2.fightTarget);
-          if (otherTarget) continue;
-        }
-
-        const distance = Math.sqrt(
-          Math.pow(entity1.x - entity2.x, 2) +
-          Math.pow(entity1.y - entity2.y, 2)
-        );
-
-        if (distance < closestDistance) {
-          closestDistance = distance;
-          closestId = id2;
-        }
-      }
-
-      // マッチング設定
-      if (closestId) {
-        state1.fightTarget = closestId;
-        const target = this.entities.get(closestId);
-        if (target && target.animationState) {
-          target.animationState.fightTarget = id1;
-          console.log(`🎯 spin_fight matched: ${id1} ↔ ${closestId} (distance: ${Math.round(closestDistance)})`);
-        }
+  endFight(data) {
+    [data.a, data.b].forEach((id) => {
+      const entity = this.entities.get(id);
+      if (entity && entity.animationState) {
+        entity.animationState.fightTarget = null;
+        entity.animationState.fightPhase = 0;
       }
+    });
+    if (data.winner && this.entities.has(data.winner)) {
+      this.addParticles(data.winner, 20, "explode");
+      console.log(`🏆 spin_fight winner: ${data.winner} (match ${data.match_id})`);
     }
   }
 
@@ -871,7 +940,10 @@ class DisplaySystem {
     switch (entity.animationKind) {
       case "pulsate":
         // より自然な脈動効果
-        const pulseIntensity = 0.3 + Math.sin(time * 1.5) * 0.2;
+        const pulseIntensity =
+          0.3 +
+          Math.sin(time * this.animationParam(entity, "speed", 1.5)) *
+            this.animationParam(entity, "amplitude", 0.2);
         entity.scale = entity.initScale * (1 + pulseIntensity);
 
         // 色の変化も追加
@@ -880,8 +952,8 @@ class DisplaySystem {
 
       case "disperse":
         // 定期的にパーティクルを生成
-        if (time - state.lastParticleTime > 0.1) {
-          this.addParticles(entityId, 3, "disperse");
+        if (time - state.lastParticleTime > this.animationParam(entity, "particle_interval", 0.1)) {
+          this.addParticles(entityId, this.animationParam(entity, "particle_count", 3), "disperse");
           state.lastParticleTime = time;
         }
 
@@ -896,7 +968,7 @@ class DisplaySystem {
       case "explode":
         // 爆発パーティクルを生成
         if (state.phase === 0) {
-          this.addParticles(entityId, 20, "explode");
+          this.addParticles(entityId, this.animationParam(entity, "particle_count", 20), "explode");
           state.phase = 1;
           state.lastParticleTime = time;
         }
@@ -907,8 +979,9 @@ class DisplaySystem {
             0,
             1 - (time - state.lastParticleTime) * 0.001
           );
-          entity.x += (Math.random() - 0.5) * shakeIntensity * 10;
-          entity.y += (Math.random() - 0.5) * shakeIntensity * 10;
+          const shake = this.animationParam(entity, "shake", 10);
+          entity.x += (Math.random() - 0.5) * shakeIntensity * shake;
+          entity.y += (Math.random() - 0.5) * shakeIntensity * shake;
 
           if (time - state.lastParticleTime > 2) {
             state.phase = 0; // リセット
@@ -921,7 +994,7 @@ class DisplaySystem {
 
       case "spin_fight":
         // ベイブレード的な戦闘システム
-        // マッチングは matchSpinFightEntities() で一括処理済み
+        // 対戦相手はサーバが fight.start で割り当てる（全ディスプレイで同じ組み合わせ）
 
         if (state.fightTarget) {
           const target = this.entities.get(state.fightTarget);
@@ -995,12 +1068,13 @@ class DisplaySystem {
               }
             } else if (distance > 50 && distance < 300) {
               // 一定距離内なら引き寄せる
-              entity.vx += (dx / distance) * 0.5;
-              entity.vy += (dy / distance) * 0.5;
+              const attract = this.animationParam(entity, "attract", 0.5);
+              entity.vx += (dx / distance) * attract;
+              entity.vy += (dy / distance) * attract;
             }
 
             // 高速回転
-            entity.angle += deltaTime * 2;
+            entity.angle += deltaTime * this.animationParam(entity, "spin_speed", 2);
           } else {
             // 相手が消えた場合
             state.fightTarget = null;
@@ -1025,7 +1099,7 @@ class DisplaySystem {
 
         if (state.phase === 1) {
           // 画面内に流れ込む
-          entity.x += deltaTime * 200;
+          entity.x += deltaTime * this.animationParam(entity, "speed", 200);
 
           // 波打つような動き
           entity.y += Math.sin(streamProgress * 2) * 2;
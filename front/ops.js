@@ -12,6 +12,30 @@ class OpsSystem {
   init() {
     this.setupEventListeners();
     this.loadSystemStatus();
+    this.loadAnimationKinds();
+  }
+
+  // アニメーション種類の選択肢をサーバのレジストリから作る（失敗時はHTMLの既定の選択肢のまま）
+  async loadAnimationKinds() {
+    try {
+      const response = await fetch("/api/animation-kinds");
+      if (!response.ok) {
+        throw new Error("Failed to load animation kinds");
+      }
+      const kinds = await response.json();
+      const select = document.getElementById("animation-select");
+      select.innerHTML = "";
+      kinds.forEach((kind) => {
+        const option = document.createElement("option");
+        option.value = kind.name;
+        option.textContent = kind.description
+          ? `${kind.description} (${kind.name})`
+          : kind.name;
+        select.appendChild(option);
+      });
+    } catch (error) {
+      console.error("Animation kinds load failed:", error);
+    }
   }
 
   setupEventListeners() {
@@ -50,22 +74,34 @@ class OpsSystem {
         this.loadSystemStatus();
       });
 
+    // 動きだけリセット（エンティティは残す）
+    document.getElementById("soft-reset-btn").addEventListener("click", () => {
+      this.softReset();
+    });
+
     // 全リセット
     document.getElementById("reset-all-btn").addEventListener("click", () => {
       this.resetAll();
     });
+
+    // 全リセットの取り消し
+    document.getElementById("undo-reset-btn").addEventListener("click", () => {
+      this.undoReset();
+    });
   }
 
   async loadArtworks() {
     try {
-      const response = await fetch("/api/artworks", {
-        headers: {
-          "X-API-Key": "ops_dev_key_12345",
-        },
-      });
-
-      if (response.ok) {
-        this.artworks = await response.json();
+      const headers = { "X-API-Key": "ops_dev_key_12345" };
+      const [response, takedownsResponse] = await Promise.all([
+        fetch("/api/artworks", { headers }),
+        fetch("/api/takedowns", { headers }),
+      ]);
+
+      if (response.ok && takedownsResponse.ok) {
+        // 取り下げ中の作品は通常の一覧に含まれないので後ろに並べる
+        const takenDown = await takedownsResponse.json();
+        this.artworks = (await response.json()).concat(takenDown);
         this.renderArtworksList();
         this.showStatus("作品一覧を読み込みました", "success");
       } else {
@@ -85,15 +121,43 @@ class OpsSystem {
       return;
     }
 
+    const statusLabels = {
+      pending: "承認待ち",
+      approved: "承認済み",
+      rejected: "却下",
+    };
+
     container.innerHTML = this.artworks
       .map((artwork) => {
         const title = artwork.title || "無題";
-        const thumbUrl = artwork.thumb_path
-          ? `/download/${artwork.qr_token}?thumb=true`
-          : "";
+        // 却下された作品はダウンロードできないのでサムネイルも出さない
+        const thumbUrl =
+          artwork.thumb_path && artwork.status !== "rejected"
+            ? `/download/${artwork.qr_token}?thumb=true`
+            : "";
+        if (artwork.taken_down_at) {
+          return this.renderTakenDownArtwork(artwork, title);
+        }
+        const moderationButtons =
+          artwork.status === "pending"
+            ? `
+              <button class="success" onclick="event.stopPropagation(); opsSystem.moderateArtworks([${artwork.id}], 'approve')">
+                承認
+              </button>
+              <button class="danger" onclick="event.stopPropagation(); opsSystem.moderateArtworks([${artwork.id}], 'reject')">
+                却下
+              </button>`
+            : "";
         return `
-          <div class="artwork-item" data-artwork-id="${artwork.id}">
-            <div class="artwork-actions">
+          <div class="artwork-item ${artwork.status}" data-artwork-id="${
+          artwork.id
+        }">
+            <div class="artwork-actions">${moderationButtons}
+              <button class="danger" onclick="event.stopPropagation(); opsSystem.takedownArtwork(${
+                artwork.id
+              })">
+                取り下げ
+              </button>
               <button class="danger" onclick="event.stopPropagation(); opsSystem.deleteArtwork(${
                 artwork.id
               })">
@@ -105,6 +169,9 @@ class OpsSystem {
               <h3>${title}</h3>
               <p>ID: ${artwork.id}</p>
               <p>QR Token: ${artwork.qr_token}</p>
+              <p>状態: ${statusLabels[artwork.status] || artwork.status}${
+          artwork.moderation_reason ? `（${artwork.moderation_reason}）` : ""
+        }</p>
             </div>
           </div>
         `;
@@ -120,6 +187,29 @@ class OpsSystem {
     });
   }
 
+  // 取り下げ中の作品は復帰だけできる（サムネイルは出さない）
+  renderTakenDownArtwork(artwork, title) {
+    const records = artwork.takedowns || [];
+    const last = records[records.length - 1];
+    const reason = last && last.reason ? `（${last.reason}）` : "";
+    const takenDownAt = new Date(artwork.taken_down_at).toLocaleString();
+    return `
+      <div class="artwork-item taken-down" data-artwork-id="${artwork.id}">
+        <div class="artwork-actions">
+          <button class="success" onclick="event.stopPropagation(); opsSystem.reinstateArtwork(${artwork.id})">
+            復帰
+          </button>
+        </div>
+        <div class="artwork-info">
+          <h3>${title}</h3>
+          <p>ID: ${artwork.id}</p>
+          <p>状態: 取り下げ中${reason}</p>
+          <p>取り下げ日時: ${takenDownAt}</p>
+        </div>
+      </div>
+    `;
+  }
+
   selectArtwork(artworkId) {
     this.selectedArtwork = this.artworks.find((a) => a.id === artworkId);
 
@@ -366,32 +456,207 @@ class OpsSystem {
     }
   }
 
+  async softReset() {
+    try {
+      const response = await fetch(`/api/scenes/${this.currentSceneId}/reset`, {
+        method: "POST",
+        headers: {
+          "X-API-Key": "ops_dev_key_12345",
+        },
+      });
+
+      if (response.ok) {
+        this.showStatus("初期位置から動きを再開しました", "success");
+      } else {
+        throw new Error("Failed to reset");
+      }
+    } catch (error) {
+      console.error("Soft reset failed:", error);
+      this.showStatus("リセットに失敗しました", "error");
+    }
+  }
+
   async resetAll() {
     if (
       !confirm(
-        "本当に全リセットを実行しますか？すべてのエンティティが削除されます。"
+        "本当に全リセットを実行しますか？すべてのエンティティが削除されます。\n（10分以内なら取り消せます）"
       )
     ) {
       return;
     }
 
     try {
-      const response = await fetch(`/api/scenes/${this.currentSceneId}/reset`, {
+      const response = await fetch(
+        `/api/scenes/${this.currentSceneId}/reset?hard=true`,
+        {
+          method: "POST",
+          headers: {
+            "X-API-Key": "ops_dev_key_12345",
+          },
+        }
+      );
+
+      if (response.ok) {
+        const result = await response.json();
+        this.showUndoReset(result.trash_id, result.expires_at);
+        this.showStatus("全リセットを実行しました", "success");
+        this.loadSystemStatus();
+      } else {
+        throw new Error("Failed to reset");
+      }
+    } catch (error) {
+      console.error("Reset failed:", error);
+      this.showStatus("リセットに失敗しました", "error");
+    }
+  }
+
+  // 取り消し期限まで取り消しボタンを表示する
+  showUndoReset(trashId, expiresAt) {
+    const button = document.getElementById("undo-reset-btn");
+    clearTimeout(this.undoResetTimer);
+    this.lastTrashId = trashId;
+    button.classList.remove("hidden");
+
+    const remaining = new Date(expiresAt).getTime() - Date.now();
+    this.undoResetTimer = setTimeout(() => this.hideUndoReset(), remaining);
+  }
+
+  hideUndoReset() {
+    clearTimeout(this.undoResetTimer);
+    this.lastTrashId = null;
+    document.getElementById("undo-reset-btn").classList.add("hidden");
+  }
+
+  async undoReset() {
+    if (!this.lastTrashId) {
+      return;
+    }
+
+    try {
+      const response = await fetch(
+        `/api/scenes/${this.currentSceneId}/trash/${this.lastTrashId}/restore`,
+        {
+          method: "POST",
+          headers: {
+            "X-API-Key": "ops_dev_key_12345",
+          },
+        }
+      );
+
+      if (response.ok) {
+        const result = await response.json();
+        this.hideUndoReset();
+        this.showStatus(
+          `全リセットを取り消しました（${result.restored}件を復元）`,
+          "success"
+        );
+        this.loadSystemStatus();
+      } else if (response.status === 409 || response.status === 410) {
+        this.hideUndoReset();
+        this.showStatus("取り消し期限が過ぎているか、取り消し済みです", "error");
+      } else {
+        throw new Error("Failed to undo reset");
+      }
+    } catch (error) {
+      console.error("Undo reset failed:", error);
+      this.showStatus("取り消しに失敗しました", "error");
+    }
+  }
+
+  // action: "approve" または "reject"（却下には理由が必要）
+  async moderateArtworks(artworkIds, action) {
+    let reason = "";
+    if (action === "reject") {
+      reason = prompt("却下の理由を入力してください");
+      if (!reason) {
+        return;
+      }
+    }
+
+    try {
+      const response = await fetch(`/api/moderation/${action}`, {
         method: "POST",
         headers: {
+          "Content-Type": "application/json",
           "X-API-Key": "ops_dev_key_12345",
         },
+        body: JSON.stringify({ artwork_ids: artworkIds, reason }),
       });
 
       if (response.ok) {
-        this.showStatus("全リセットを実行しました", "success");
+        const result = await response.json();
+        const count = (result.approved || result.rejected || []).length;
+        this.showStatus(
+          `${count}件を${action === "approve" ? "承認" : "却下"}しました`,
+          "success"
+        );
+        this.loadArtworks();
+      } else {
+        const errorData = await response.json();
+        throw new Error(errorData.error || "Failed to moderate artworks");
+      }
+    } catch (error) {
+      console.error("Moderation failed:", error);
+      this.showStatus(`審査に失敗しました: ${error.message}`, "error");
+    }
+  }
+
+  async takedownArtwork(artworkId) {
+    const reason = prompt(
+      "取り下げの理由を入力してください（作品はすべてのシーンから外れ、ダウンロードもできなくなります）"
+    );
+    if (!reason) {
+      return;
+    }
+
+    try {
+      const response = await fetch(`/api/artworks/${artworkId}/takedown`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          "X-API-Key": "ops_dev_key_12345",
+        },
+        body: JSON.stringify({ reason }),
+      });
+
+      if (response.ok) {
+        this.showStatus("作品を取り下げました", "success");
+        this.loadArtworks();
         this.loadSystemStatus();
       } else {
-        throw new Error("Failed to reset");
+        const errorData = await response.json();
+        throw new Error(errorData.error || "Failed to take down artwork");
       }
     } catch (error) {
-      console.error("Reset failed:", error);
-      this.showStatus("リセットに失敗しました", "error");
+      console.error("Takedown failed:", error);
+      this.showStatus(`取り下げに失敗しました: ${error.message}`, "error");
+    }
+  }
+
+  async reinstateArtwork(artworkId) {
+    if (!confirm("この作品を復帰させますか？取り下げ前に表示していたシーンに戻ります。")) {
+      return;
+    }
+
+    try {
+      const response = await fetch(`/api/artworks/${artworkId}/reinstate`, {
+        method: "POST",
+        headers: {
+          "X-API-Key": "ops_dev_key_12345",
+        },
+      });
+
+      if (response.ok) {
+        this.showStatus("作品を復帰させました", "success");
+        this.loadArtworks();
+        this.loadSystemStatus();
+      } else {
+        const errorData = await response.json();
+        throw new Error(errorData.error || "Failed to reinstate artwork");
+      }
+    } catch (error) {
+      console.error("Reinstate failed:", error);
+      this.showStatus(`復帰に失敗しました: ${error.message}`, "error");
     }
   }
 